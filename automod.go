@@ -0,0 +1,413 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ViolationKind is a rule broken by a message, see AutoMod.
+type ViolationKind string
+
+const (
+	ViolationFlood       ViolationKind = "flood"
+	ViolationRepeat      ViolationKind = "repeat"
+	ViolationMassMention ViolationKind = "mass_mention"
+	ViolationBlocklist   ViolationKind = "blocklist"
+)
+
+// Violation is a message AutoMod acted on.
+type Violation struct {
+	Kind    ViolationKind
+	GroupID int64
+	UserID  int64
+	Message *ReceiveMessage
+
+	// What matched, such as the blocklisted keyword or domain.
+	Detail string
+
+	// How many violations the user has had within AutoMod.StrikeWindow,
+	// including this one. MuteAction escalates with it.
+	Strike int
+}
+
+// ModerationStore keeps AutoMod's counters, so they can be shared between
+// processes. NewMemoryStore returns one kept in memory.
+type ModerationStore interface {
+	// Hit records a hit on key at now and returns how many hits key had
+	// within window before now, including this one.
+	Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int, error)
+}
+
+// MemoryStore is a ModerationStore kept in memory.
+type MemoryStore struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+	ops  int
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hits: map[string][]time.Time{}}
+}
+
+// Hit implements ModerationStore.
+func (m *MemoryStore) Hit(ctx context.Context, key string, now time.Time, window time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hits := append(prune(m.hits[key], now.Add(-window)), now)
+	m.hits[key] = hits
+
+	// Drop idle keys now and then so the map does not grow forever. Hits
+	// older than a day are not needed by any sensible window.
+	if m.ops++; m.ops%1024 == 0 {
+		for k, v := range m.hits {
+			if v = prune(v, now.Add(-24*time.Hour)); len(v) == 0 {
+				delete(m.hits, k)
+			} else {
+				m.hits[k] = v
+			}
+		}
+	}
+	return len(hits), nil
+}
+
+// prune drops the hits before since.
+func prune(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && hits[i].Before(since) {
+		i++
+	}
+	return hits[i:]
+}
+
+// ModAction is run by AutoMod on a violation.
+type ModAction func(ctx context.Context, s *Session, v *Violation) error
+
+// RecallAction recalls the offending message.
+func RecallAction() ModAction {
+	return func(ctx context.Context, s *Session, v *Violation) error {
+		return s.RecallGroupMessage(v.GroupID, v.Message.MessageSeq, WithContext(ctx))
+	}
+}
+
+// MuteAction mutes the offender, for durations[0] on their first strike,
+// durations[1] on the second and so on, staying at the last duration.
+func MuteAction(durations ...time.Duration) ModAction {
+	return func(ctx context.Context, s *Session, v *Violation) error {
+		if len(durations) == 0 {
+			return nil
+		}
+		i := v.Strike - 1
+		if i >= len(durations) {
+			i = len(durations) - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		return s.SetGroupMemberMuteDuration(v.GroupID, v.UserID, durations[i], WithContext(ctx))
+	}
+}
+
+// KickAction kicks the offender once they have at least strikes strikes.
+func KickAction(strikes int, rejectAddRequest bool) ModAction {
+	return func(ctx context.Context, s *Session, v *Violation) error {
+		if v.Strike < strikes {
+			return nil
+		}
+		return s.KickGroupMember(v.GroupID, v.UserID, rejectAddRequest, WithContext(ctx))
+	}
+}
+
+// NotifyAdminsAction posts a notice in the group mentioning its owner and
+// admins.
+func NotifyAdminsAction() ModAction {
+	return func(ctx context.Context, s *Session, v *Violation) error {
+		members, err := s.GetGroupMemberList(v.GroupID, false, WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		var message []IMessageElement
+		for _, m := range members {
+			if m.Role == RoleOwner || m.Role == RoleAdmin {
+				message = append(message, &AtElement{UserID: m.UserId})
+			}
+		}
+		text := fmt.Sprintf(" automod: %s by %d (strike %d)", v.Kind, v.UserID, v.Strike)
+		if v.Detail != "" {
+			text += ": " + v.Detail
+		}
+		message = append(message, &TextElement{Text: text})
+		_, err = s.SendGroupMessage(v.GroupID, &message, WithContext(ctx))
+		return err
+	}
+}
+
+// Windows AutoMod uses when its window fields are left zero.
+const (
+	DefaultFloodWindow  = 5 * time.Second
+	DefaultRepeatWindow = time.Minute
+	DefaultStrikeWindow = 24 * time.Hour
+)
+
+// AutoMod moderates group messages. It detects floods, repeated messages,
+// mass mentions and blocklisted keywords or domains, and runs the configured
+// actions on the offender. Attach it to a session with Attach.
+type AutoMod struct {
+	// Store keeps the counters. When nil, a MemoryStore is used.
+	Store ModerationStore
+
+	// More than FloodMessages messages from a user within FloodWindow is a
+	// flood. 0 disables the check, a zero window is DefaultFloodWindow.
+	FloodMessages int
+	FloodWindow   time.Duration
+
+	// More than RepeatMessages identical messages from a user within
+	// RepeatWindow is a repeat. 0 disables the check, a zero window is
+	// DefaultRepeatWindow.
+	RepeatMessages int
+	RepeatWindow   time.Duration
+
+	// A message mentioning MaxMentions users or more is a mass mention, as
+	// is any mention of everyone unless AllowMentionAll is set. 0 disables
+	// the mention count.
+	MaxMentions     int
+	AllowMentionAll bool
+
+	// Keywords are matched case-insensitively against the text of messages.
+	// Domains match URLs on the domain or its subdomains.
+	BlockedKeywords []string
+	BlockedDomains  []string
+
+	// Members with these roles are not moderated.
+	ExemptRoles []Role
+
+	// Violations within StrikeWindow count towards a user's strikes. Zero
+	// is DefaultStrikeWindow. A flood or repeat burst is a single strike,
+	// however many of its messages are over the limit.
+	StrikeWindow time.Duration
+
+	// Actions run for each kind of violation, in order. Errors are logged
+	// and the remaining actions still run.
+	Actions map[ViolationKind][]ModAction
+
+	// Called for every violation, after the actions.
+	OnViolation func(s *Session, v *Violation)
+
+	memoryOnce sync.Once
+	memory     *MemoryStore
+}
+
+// NewAutoMod returns an AutoMod with conservative defaults: more than 5
+// messages in 5 seconds, the same message more than 3 times in a minute or
+// 10 mentions is a violation, owners and admins are exempt, and violations
+// are recalled and muted for 10 minutes, an hour, then a day.
+func NewAutoMod(store ModerationStore) *AutoMod {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	actions := []ModAction{RecallAction(), MuteAction(10*time.Minute, time.Hour, 24*time.Hour)}
+	return &AutoMod{
+		Store:          store,
+		FloodMessages:  5,
+		FloodWindow:    DefaultFloodWindow,
+		RepeatMessages: 3,
+		RepeatWindow:   DefaultRepeatWindow,
+		MaxMentions:    10,
+		ExemptRoles:    []Role{RoleOwner, RoleAdmin},
+		StrikeWindow:   DefaultStrikeWindow,
+		Actions: map[ViolationKind][]ModAction{
+			ViolationFlood:       actions,
+			ViolationRepeat:      actions,
+			ViolationMassMention: actions,
+			ViolationBlocklist:   actions,
+		},
+	}
+}
+
+// Attach moderates the messages received by s. It returns a function that
+// detaches it again.
+func (a *AutoMod) Attach(s *Session) func() {
+	return s.AddHandler(ContextHandler(a.Handle))
+}
+
+// Handle checks a message and runs the actions for its violation, if any.
+func (a *AutoMod) Handle(ctx context.Context, s *Session, m *ReceiveMessage) {
+	v, err := a.Check(ctx, s, m)
+	if err != nil {
+		s.log().Errorf("automod: checking message %d, %s", m.MessageSeq, err)
+		return
+	}
+	if v == nil {
+		return
+	}
+	log := s.log().With("group_id", v.GroupID, "user_id", v.UserID)
+	log.Infof("automod: %s (strike %d) %s", v.Kind, v.Strike, v.Detail)
+	for _, action := range a.Actions[v.Kind] {
+		if err = action(ctx, s, v); err != nil {
+			log.Warnf("automod: action on %s failed, %s", v.Kind, err)
+		}
+	}
+	if a.OnViolation != nil {
+		a.OnViolation(s, v)
+	}
+}
+
+// Check returns the violation of m, or nil. It records m in the store, so
+// every message should be checked once.
+func (a *AutoMod) Check(ctx context.Context, s *Session, m *ReceiveMessage) (*Violation, error) {
+	if m.MessageScene != SceneGroup || m.FromSelf || a.exempt(s, m) {
+		return nil, nil
+	}
+	v := &Violation{GroupID: m.PeerId, UserID: m.SenderId, Message: m}
+	store := a.store()
+	now := time.Now()
+	user := fmt.Sprintf("%d:%d", m.PeerId, m.SenderId)
+	text := PlainText(m.Segments)
+
+	// Every message counts towards floods and repeats, so record them
+	// before looking for other violations.
+	var window time.Duration
+	if a.FloodMessages > 0 {
+		window = orDefault(a.FloodWindow, DefaultFloodWindow)
+		n, err := store.Hit(ctx, "flood:"+user, now, window)
+		if err != nil {
+			return nil, err
+		}
+		if n > a.FloodMessages {
+			v.Kind, v.Detail = ViolationFlood, fmt.Sprintf("%d messages in %s", n, window)
+		}
+	}
+	if a.RepeatMessages > 0 && text != "" {
+		h := fnv.New64a()
+		h.Write([]byte(text))
+		repeatWindow := orDefault(a.RepeatWindow, DefaultRepeatWindow)
+		n, err := store.Hit(ctx, fmt.Sprintf("repeat:%s:%x", user, h.Sum64()), now, repeatWindow)
+		if err != nil {
+			return nil, err
+		}
+		if n > a.RepeatMessages {
+			v.Kind, v.Detail = ViolationRepeat, fmt.Sprintf("%d identical messages in %s", n, repeatWindow)
+			window = repeatWindow
+		}
+	}
+	if detail := a.massMention(m.Segments); detail != "" {
+		v.Kind, v.Detail = ViolationMassMention, detail
+	}
+	if detail := a.blocked(text); detail != "" {
+		v.Kind, v.Detail = ViolationBlocklist, detail
+	}
+	if v.Kind == "" {
+		return nil, nil
+	}
+
+	// The messages of one flood or repeat burst are a single incident. It
+	// ends once the user sent no violating message for a whole window.
+	if v.Kind == ViolationFlood || v.Kind == ViolationRepeat {
+		n, err := store.Hit(ctx, fmt.Sprintf("incident:%s:%s", v.Kind, user), now, window)
+		if err != nil {
+			return nil, err
+		}
+		if n > 1 {
+			return nil, nil
+		}
+	}
+
+	strikes, err := store.Hit(ctx, "strike:"+user, now, orDefault(a.StrikeWindow, DefaultStrikeWindow))
+	if err != nil {
+		return nil, err
+	}
+	v.Strike = strikes
+	return v, nil
+}
+
+// store returns Store, or a MemoryStore for an AutoMod built without one.
+func (a *AutoMod) store() ModerationStore {
+	if a.Store != nil {
+		return a.Store
+	}
+	a.memoryOnce.Do(func() { a.memory = NewMemoryStore() })
+	return a.memory
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// exempt reports whether the sender has an exempt role, read from the
+// message or else from the session's role cache.
+func (a *AutoMod) exempt(s *Session, m *ReceiveMessage) bool {
+	if len(a.ExemptRoles) == 0 {
+		return false
+	}
+	var role Role
+	if m.GroupMember != nil {
+		role = m.GroupMember.Role
+	} else if r, err := s.MemberRole(m.PeerId, m.SenderId); err == nil {
+		role = r
+	}
+	for _, exempt := range a.ExemptRoles {
+		if role == exempt {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AutoMod) massMention(segments []IMessageElement) string {
+	mentioned := map[int64]bool{}
+	for _, segment := range segments {
+		switch e := segment.(type) {
+		case *AtAllElement:
+			if !a.AllowMentionAll {
+				return "mentioned everyone"
+			}
+		case *AtElement:
+			mentioned[e.UserID] = true
+		}
+	}
+	if a.MaxMentions > 0 && len(mentioned) >= a.MaxMentions {
+		return fmt.Sprintf("mentioned %d users", len(mentioned))
+	}
+	return ""
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[a-z0-9-]+\.)+[a-z]{2,}(?::\d+)?(?:/\S*)?`)
+
+func (a *AutoMod) blocked(text string) string {
+	lower := strings.ToLower(text)
+	for _, keyword := range a.BlockedKeywords {
+		if keyword != "" && strings.Contains(lower, strings.ToLower(keyword)) {
+			return "keyword " + keyword
+		}
+	}
+	if len(a.BlockedDomains) == 0 {
+		return ""
+	}
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		if !strings.Contains(match, "://") {
+			match = "http://" + match
+		}
+		u, err := url.Parse(match)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		for _, domain := range a.BlockedDomains {
+			domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return "domain " + domain
+			}
+		}
+	}
+	return ""
+}
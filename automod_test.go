@@ -0,0 +1,161 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoModCheck(t *testing.T) {
+	s, _ := New("", "", "", &TestLogger{})
+	s.LogLevel = LogError
+	a := NewAutoMod(nil)
+	a.FloodMessages = 3
+	a.BlockedKeywords = []string{"Free Coins"}
+	a.BlockedDomains = []string{"spam.example"}
+	ctx := context.Background()
+
+	seq := int64(0)
+	message := func(user int64, role Role, segments ...IMessageElement) *ReceiveMessage {
+		seq++
+		return &ReceiveMessage{
+			MessageScene: SceneGroup, PeerId: 1, SenderId: user, MessageSeq: seq, Segments: segments,
+			GroupMember: &GroupMemberInfo{GroupId: 1, UserId: user, Role: role},
+		}
+	}
+	check := func(m *ReceiveMessage) *Violation {
+		t.Helper()
+		v, err := a.Check(ctx, s, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, tc := range []struct {
+		m    *ReceiveMessage
+		want ViolationKind
+	}{
+		{message(10, RoleMember, &TextElement{Text: "get FREE COINS now"}), ViolationBlocklist},
+		{message(11, RoleMember, &TextElement{Text: "see www.spam.example/win"}), ViolationBlocklist},
+		{message(12, RoleMember, &TextElement{Text: "see https://example.com"}), ""},
+		{message(13, RoleMember, &AtAllElement{}), ViolationMassMention},
+		{message(14, RoleAdmin, &AtAllElement{}), ""},
+	} {
+		v := check(tc.m)
+		if (v == nil && tc.want != "") || (v != nil && v.Kind != tc.want) {
+			t.Errorf("%s: got %+v, want %q", PlainText(tc.m.Segments), v, tc.want)
+		}
+	}
+
+	// The fourth message in the flood window is a flood and repeats of the
+	// same text past the limit are repeats, but one burst is one strike.
+	var kinds []ViolationKind
+	var strikes []int
+	for i := 0; i < 5; i++ {
+		if v := check(message(20, RoleMember, &TextElement{Text: "same"})); v != nil {
+			kinds = append(kinds, v.Kind)
+			strikes = append(strikes, v.Strike)
+		}
+	}
+	if len(kinds) != 1 || kinds[0] != ViolationRepeat || strikes[0] != 1 {
+		t.Errorf("got %v with strikes %v", kinds, strikes)
+	}
+	a.RepeatMessages = 0
+	for i := 0; i < 3; i++ {
+		check(message(21, RoleMember, &TextElement{Text: string(rune('a' + i))}))
+	}
+	if v := check(message(21, RoleMember, &TextElement{Text: "d"})); v == nil || v.Kind != ViolationFlood {
+		t.Errorf("expected a flood, got %+v", v)
+	}
+}
+
+func TestAutoModZeroValue(t *testing.T) {
+	s, _ := New("", "", "", &TestLogger{})
+	a := &AutoMod{FloodMessages: 2}
+	var strikes []int
+	for i := 0; i < 4; i++ {
+		v, err := a.Check(context.Background(), s, &ReceiveMessage{MessageScene: SceneGroup, PeerId: 1, SenderId: 10, Segments: []IMessageElement{&TextElement{Text: "hi"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != nil {
+			strikes = append(strikes, v.Strike)
+		}
+	}
+	if len(strikes) != 1 || strikes[0] != 1 {
+		t.Fatalf("expected one flood strike, got %v", strikes)
+	}
+}
+
+func TestAutoModOneStrikePerIncident(t *testing.T) {
+	s, _ := New("", "", "", &TestLogger{})
+	a := &AutoMod{FloodMessages: 2, FloodWindow: 50 * time.Millisecond}
+	var strikes []int
+	for burst := 0; burst < 2; burst++ {
+		if burst > 0 {
+			time.Sleep(120 * time.Millisecond)
+		}
+		for i := 0; i < 10; i++ {
+			v, err := a.Check(context.Background(), s, &ReceiveMessage{MessageScene: SceneGroup, PeerId: 1, SenderId: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != nil {
+				strikes = append(strikes, v.Strike)
+			}
+		}
+	}
+	if len(strikes) != 2 || strikes[0] != 1 || strikes[1] != 2 {
+		t.Fatalf("expected one strike per burst, got %v", strikes)
+	}
+}
+
+func TestAutoModActions(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/"))
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{}}`))
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	a := NewAutoMod(NewMemoryStore())
+	a.BlockedKeywords = []string{"spam"}
+	a.Actions[ViolationBlocklist] = append(a.Actions[ViolationBlocklist], KickAction(2, false))
+	var violations []*Violation
+	a.OnViolation = func(s *Session, v *Violation) { violations = append(violations, v) }
+
+	for seq := int64(1); seq <= 2; seq++ {
+		a.Handle(context.Background(), s, &ReceiveMessage{
+			MessageScene: SceneGroup, PeerId: 1, SenderId: 2, MessageSeq: seq,
+			Segments:    []IMessageElement{&TextElement{Text: "spam"}},
+			GroupMember: &GroupMemberInfo{Role: RoleMember},
+		})
+	}
+	want := []string{
+		EndpointRecallGroupMessage, EndpointSetGroupMemberMute,
+		EndpointRecallGroupMessage, EndpointSetGroupMemberMute, EndpointKickGroupMember,
+	}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+	if len(violations) != 2 || violations[1].Strike != 2 {
+		t.Errorf("unexpected violations %+v", violations)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	for i, want := range []int{1, 2, 2} {
+		n, _ := store.Hit(context.Background(), "k", now.Add(time.Duration(i)*time.Second), 1500*time.Millisecond)
+		if n != want {
+			t.Errorf("hit %d: got %d, want %d", i, n, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultBulkConcurrency = 4
+
+// Limiter paces bulk operations. *rate.Limiter from golang.org/x/time/rate
+// implements it.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// bulkConfig holds the settings of a bulk operation.
+type bulkConfig struct {
+	Concurrency int
+	Limiter     Limiter
+	OnProgress  func(BulkProgress)
+}
+
+// BulkOption is a function which mutates bulk operation configuration.
+type BulkOption func(cfg *bulkConfig)
+
+// WithConcurrency changes how many items are processed at once.
+func WithConcurrency(n int) BulkOption {
+	return func(cfg *bulkConfig) {
+		if n > 0 {
+			cfg.Concurrency = n
+		}
+	}
+}
+
+// WithLimiter waits on l before each item.
+func WithLimiter(l Limiter) BulkOption {
+	return func(cfg *bulkConfig) {
+		cfg.Limiter = l
+	}
+}
+
+// WithProgress calls fn after each item. Calls are not concurrent.
+func WithProgress(fn func(BulkProgress)) BulkOption {
+	return func(cfg *bulkConfig) {
+		cfg.OnProgress = fn
+	}
+}
+
+// BulkStatus is the outcome of one item of a bulk operation.
+type BulkStatus int
+
+const (
+	// BulkSkipped items were not attempted because the context ended first.
+	BulkSkipped BulkStatus = iota
+	BulkSucceeded
+	// BulkFailed items returned an error. When the error is the context's,
+	// the request may or may not have taken effect.
+	BulkFailed
+)
+
+func (s BulkStatus) String() string {
+	switch s {
+	case BulkSucceeded:
+		return "succeeded"
+	case BulkFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// BulkResult is the outcome of one item.
+type BulkResult[T any] struct {
+	Item   T
+	Status BulkStatus
+	Err    error
+}
+
+// BulkProgress is passed to WithProgress callbacks.
+type BulkProgress struct {
+	Total     int
+	Succeeded int
+	Failed    int
+	Elapsed   time.Duration
+}
+
+// BulkReport records the outcome of every item of a bulk operation.
+type BulkReport[T any] struct {
+	// Results in the order the items were given.
+	Results []BulkResult[T]
+
+	// why items were skipped
+	cause error
+}
+
+// Succeeded returns the items that were done.
+func (r *BulkReport[T]) Succeeded() []T { return r.items(BulkSucceeded) }
+
+// Failed returns the items that returned an error.
+func (r *BulkReport[T]) Failed() []T { return r.items(BulkFailed) }
+
+// Skipped returns the items that were not attempted.
+func (r *BulkReport[T]) Skipped() []T { return r.items(BulkSkipped) }
+
+func (r *BulkReport[T]) items(status BulkStatus) []T {
+	var items []T
+	for _, result := range r.Results {
+		if result.Status == status {
+			items = append(items, result.Item)
+		}
+	}
+	return items
+}
+
+// Err returns the errors of failed items joined, or nil when all succeeded.
+func (r *BulkReport[T]) Err() error {
+	var errs []error
+	skipped := 0
+	for _, result := range r.Results {
+		switch result.Status {
+		case BulkFailed:
+			errs = append(errs, fmt.Errorf("%v: %w", result.Item, result.Err))
+		case BulkSkipped:
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		errs = append(errs, fmt.Errorf("%d items skipped: %w", skipped, r.cause))
+	}
+	return errors.Join(errs...)
+}
+
+// runBulk calls fn for every item with bounded concurrency. Once ctx is done
+// remaining items are skipped.
+func runBulk[T any](ctx context.Context, items []T, options []BulkOption, fn func(ctx context.Context, item T) error) (*BulkReport[T], error) {
+	cfg := &bulkConfig{Concurrency: defaultBulkConcurrency}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	report := &BulkReport[T]{Results: make([]BulkResult[T], len(items))}
+	for i, item := range items {
+		report.Results[i].Item = item
+	}
+
+	var mu sync.Mutex
+	progress := BulkProgress{Total: len(items)}
+	start := time.Now()
+	record := func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			report.Results[i].Status, report.Results[i].Err = BulkFailed, err
+			progress.Failed++
+		} else {
+			report.Results[i].Status = BulkSucceeded
+			progress.Succeeded++
+		}
+		if cfg.OnProgress != nil {
+			progress.Elapsed = time.Since(start)
+			cfg.OnProgress(progress)
+		}
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil {
+					continue // skipped
+				}
+				if cfg.Limiter != nil {
+					if err := cfg.Limiter.Wait(ctx); err != nil {
+						if ctx.Err() != nil {
+							continue // skipped
+						}
+						record(i, err)
+						continue
+					}
+				}
+				record(i, fn(ctx, items[i]))
+			}
+		}()
+	}
+feed:
+	for i := range items {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	report.cause = ctx.Err()
+
+	return report, report.Err()
+}
+
+// BulkKickGroupMembers kicks userIDs from groupID, see KickGroupMember.
+func (s *Session) BulkKickGroupMembers(ctx context.Context, groupID int64, userIDs []int64, rejectAddRequest bool, options ...BulkOption) (*BulkReport[int64], error) {
+	return runBulk(ctx, userIDs, options, func(ctx context.Context, userID int64) error {
+		return s.KickGroupMember(groupID, userID, rejectAddRequest, WithContext(ctx))
+	})
+}
+
+// BulkMuteGroupMembers mutes userIDs in groupID for d, 0 unmutes them. See
+// SetGroupMemberMuteDuration.
+func (s *Session) BulkMuteGroupMembers(ctx context.Context, groupID int64, userIDs []int64, d time.Duration, options ...BulkOption) (*BulkReport[int64], error) {
+	if err := validMuteDuration(d); err != nil {
+		return nil, err
+	}
+	return runBulk(ctx, userIDs, options, func(ctx context.Context, userID int64) error {
+		return s.SetGroupMemberMuteDuration(groupID, userID, d, WithContext(ctx))
+	})
+}
+
+// BulkRecallGroupMessages recalls messageSeqs in groupID, see
+// RecallGroupMessage.
+func (s *Session) BulkRecallGroupMessages(ctx context.Context, groupID int64, messageSeqs []int64, options ...BulkOption) (*BulkReport[int64], error) {
+	return runBulk(ctx, messageSeqs, options, func(ctx context.Context, messageSeq int64) error {
+		return s.RecallGroupMessage(groupID, messageSeq, WithContext(ctx))
+	})
+}
+
+// BulkDeleteGroupFiles deletes fileIDs from groupID, see DeleteGroupFile.
+func (s *Session) BulkDeleteGroupFiles(ctx context.Context, groupID int64, fileIDs []string, options ...BulkOption) (*BulkReport[string], error) {
+	return runBulk(ctx, fileIDs, options, func(ctx context.Context, fileID string) error {
+		return s.DeleteGroupFile(groupID, fileID, WithContext(ctx))
+	})
+}
@@ -0,0 +1,90 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkKickGroupMembers(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var req struct {
+			UserID int64 `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.UserID%3 == 0 {
+			fmt.Fprint(w, `{"status":"failed","retcode":-1,"message":"cannot kick"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"ok","retcode":0,"data":{}}`)
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	var mu sync.Mutex
+	var last BulkProgress
+	calls := 0
+	report, err := s.BulkKickGroupMembers(context.Background(), 1, []int64{1, 2, 3, 4, 5, 6, 7}, false,
+		WithConcurrency(2),
+		WithProgress(func(p BulkProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			last = p
+		}))
+	if err == nil {
+		t.Fatal("expected an error for the failed items")
+	}
+	if got := report.Failed(); len(got) != 2 || got[0] != 3 || got[1] != 6 {
+		t.Errorf("failed items %v, want [3 6]", got)
+	}
+	if len(report.Succeeded()) != 5 || len(report.Skipped()) != 0 {
+		t.Errorf("unexpected report %+v", report.Results)
+	}
+	if calls != 7 || last.Succeeded != 5 || last.Failed != 2 || last.Total != 7 {
+		t.Errorf("unexpected progress %+v after %d calls", last, calls)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("%d requests in flight, concurrency is 2", maxInFlight)
+	}
+}
+
+func TestBulkCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := []int{1, 2, 3, 4, 5}
+	report, err := runBulk(ctx, items, []BulkOption{WithConcurrency(1)}, func(ctx context.Context, item int) error {
+		if item == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if got := report.Succeeded(); len(got) != 2 {
+		t.Errorf("succeeded %v, want [1 2]", got)
+	}
+	if got := report.Skipped(); len(got) != 3 || got[0] != 3 {
+		t.Errorf("skipped %v, want [3 4 5]", got)
+	}
+}
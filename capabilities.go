@@ -0,0 +1,177 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MilkyVersion is the version of the Milky protocol this SDK implements.
+const MilkyVersion = milkyVersion
+
+// ErrUnsupported is matched by errors.Is for every UnsupportedError.
+var ErrUnsupported = errors.New("not supported by the implementation")
+
+// UnsupportedError is returned for calls to endpoints the connected
+// implementation does not support, without sending the request once that is
+// known.
+type UnsupportedError struct {
+	Endpoint string
+	Impl     ImplInfo
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("endpoint %s is not supported by %s %s (Milky %s)", e.Endpoint, e.Impl.ImplName, e.Impl.ImplVersion, e.Impl.MilkyVersion)
+}
+
+// Is makes errors.Is(err, ErrUnsupported) true.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported
+}
+
+// ProtocolVersion is a parsed Milky protocol version.
+type ProtocolVersion struct {
+	Major, Minor, Patch int
+}
+
+// ParseProtocolVersion parses versions such as "1.0", "1.0.0" or "v1.1".
+func ParseProtocolVersion(v string) (ProtocolVersion, error) {
+	var pv ProtocolVersion
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return pv, fmt.Errorf("invalid Milky version %q", v)
+	}
+	fields := []*int{&pv.Major, &pv.Minor, &pv.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return pv, fmt.Errorf("invalid Milky version %q", v)
+		}
+		*fields[i] = n
+	}
+	return pv, nil
+}
+
+func (v ProtocolVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less reports whether v is an earlier version than o.
+func (v ProtocolVersion) Less(o ProtocolVersion) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+// Capabilities records what the connected implementation supports. Milky
+// implementations do not list their endpoints and every endpoint this SDK
+// knows exists since Milky 1.0, so support is learned from the endpoints
+// that answered HTTP 404.
+type Capabilities struct {
+	Impl    ImplInfo
+	Version ProtocolVersion
+
+	mu          sync.RWMutex
+	unsupported map[string]bool
+}
+
+// newCapabilities returns the capabilities of impl.
+func newCapabilities(impl ImplInfo) (*Capabilities, error) {
+	version, err := ParseProtocolVersion(impl.MilkyVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Capabilities{Impl: impl, Version: version, unsupported: map[string]bool{}}, nil
+}
+
+// SupportsEndpoint reports whether the implementation supports an API
+// endpoint. Endpoints are assumed supported until they answer HTTP 404.
+func (c *Capabilities) SupportsEndpoint(endpoint string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.unsupported[endpoint]
+}
+
+// Unsupported returns the endpoints found to be unsupported so far, sorted.
+func (c *Capabilities) Unsupported() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var endpoints []string
+	for endpoint := range c.unsupported {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// markUnsupported records that endpoint answered HTTP 404.
+func (c *Capabilities) markUnsupported(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unsupported[endpoint] = true
+}
+
+// Handshake asks the implementation for its version with GetImplInfo and
+// records its capabilities on the session. Open calls it on every connect.
+func (s *Session) Handshake(options ...RequestOption) (*Capabilities, error) {
+	impl, err := s.GetImplInfo(options...)
+	if err != nil {
+		return nil, err
+	}
+	caps, err := newCapabilities(*impl)
+	if err != nil {
+		return nil, err
+	}
+
+	s.gatewayMu.Lock()
+	s.capabilities = caps
+	s.gatewayMu.Unlock()
+
+	log := s.log().With("impl", impl.ImplName, "impl_version", impl.ImplVersion)
+	sdk, _ := ParseProtocolVersion(MilkyVersion)
+	switch {
+	case caps.Version.Major != sdk.Major:
+		log.Errorf("MILKY VERSION MISMATCH: the implementation speaks Milky %s but this SDK implements Milky %s, expect failures", impl.MilkyVersion, MilkyVersion)
+	case caps.Version.Less(sdk):
+		log.Warnf("the implementation speaks Milky %s, older than Milky %s implemented by this SDK, some calls will be unsupported", impl.MilkyVersion, MilkyVersion)
+	case sdk.Less(caps.Version):
+		log.Infof("the implementation speaks Milky %s, newer than Milky %s implemented by this SDK", impl.MilkyVersion, MilkyVersion)
+	}
+	return caps, nil
+}
+
+// Capabilities returns what the connected implementation supports, or nil
+// before the first successful Handshake.
+func (s *Session) Capabilities() *Capabilities {
+	s.gatewayMu.RLock()
+	defer s.gatewayMu.RUnlock()
+	return s.capabilities
+}
+
+// checkEndpoint fails fast for endpoints known to be unsupported.
+func (s *Session) checkEndpoint(endpoint string) error {
+	if caps := s.Capabilities(); caps != nil && !caps.SupportsEndpoint(endpoint) {
+		return &UnsupportedError{Endpoint: endpoint, Impl: caps.Impl}
+	}
+	return nil
+}
+
+// unsupportedResponse turns an HTTP 404 for endpoint into an UnsupportedError
+// once the implementation is known, and remembers it for later calls.
+func (s *Session) unsupportedResponse(endpoint string, err error) error {
+	var restErr *RESTError
+	caps := s.Capabilities()
+	if caps == nil || !errors.As(err, &restErr) || restErr.Response.StatusCode != http.StatusNotFound {
+		return err
+	}
+	caps.markUnsupported(endpoint)
+	return &UnsupportedError{Endpoint: endpoint, Impl: caps.Impl}
+}
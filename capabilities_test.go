@@ -0,0 +1,77 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseProtocolVersion(t *testing.T) {
+	for in, want := range map[string]ProtocolVersion{
+		"1.0":     {1, 0, 0},
+		"1.2.3":   {1, 2, 3},
+		"v1.1":    {1, 1, 0},
+		" 2.0 ":   {2, 0, 0},
+		"1":       {},
+		"1.x":     {},
+		"1.0.0.0": {},
+	} {
+		got, err := ParseProtocolVersion(in)
+		if (want == ProtocolVersion{}) {
+			if err == nil {
+				t.Errorf("%q: expected an error", in)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("%q: got %v, %v; want %v", in, got, err, want)
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	var sends int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, EndpointGetImplInfo):
+			fmt.Fprint(w, `{"status":"ok","retcode":0,"data":{"impl_name":"old","impl_version":"0.1","milky_version":"0.9"}}`)
+		case strings.HasSuffix(r.URL.Path, EndpointSendGroupNudge):
+			atomic.AddInt32(&sends, 1)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			fmt.Fprint(w, `{"status":"ok","retcode":0,"data":{}}`)
+		}
+	}))
+	defer srv.Close()
+
+	l := &bufferLogger{}
+	s, _ := New("", srv.URL, "", l)
+	caps, err := s.Handshake()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Version != (ProtocolVersion{0, 9, 0}) || s.Capabilities() != caps || caps.Impl.ImplName != "old" {
+		t.Errorf("unexpected capabilities %+v", caps)
+	}
+	if !strings.Contains(l.String(), "ERROR MILKY VERSION MISMATCH") {
+		t.Errorf("version mismatch not logged:\n%s", l.String())
+	}
+
+	for i := 0; i < 2; i++ {
+		err = s.SendGroupNudge(1, 2)
+		var unsupported *UnsupportedError
+		if !errors.Is(err, ErrUnsupported) || !errors.As(err, &unsupported) || unsupported.Endpoint != EndpointSendGroupNudge {
+			t.Fatalf("call %d: expected ErrUnsupported, got %v", i, err)
+		}
+	}
+	if sends != 1 {
+		t.Errorf("unsupported endpoint requested %d times, want 1", sends)
+	}
+	if caps.SupportsEndpoint(EndpointSendGroupNudge) || !caps.SupportsEndpoint(EndpointGetLoginInfo) {
+		t.Errorf("unexpected endpoint support, unsupported %v", caps.Unsupported())
+	}
+}
@@ -0,0 +1,346 @@
+package Milky_go_sdk
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Challenge is a question a new member must answer.
+type Challenge struct {
+	Prompt string
+	Check  func(answer string) bool
+}
+
+// ChallengeFunc returns the challenge for a new member. Set
+// MemberVerifier.Challenge to use custom challenges.
+type ChallengeFunc func(groupID, userID int64) Challenge
+
+// ArithmeticChallenge asks for the sum of two small random numbers.
+func ArithmeticChallenge(groupID, userID int64) Challenge {
+	a, b := rand.Intn(20)+1, rand.Intn(20)+1
+	return Challenge{
+		Prompt: fmt.Sprintf("what is %d + %d?", a, b),
+		Check: func(answer string) bool {
+			n, err := strconv.Atoi(strings.TrimSpace(answer))
+			return err == nil && n == a+b
+		},
+	}
+}
+
+// QuestionChallenge returns a ChallengeFunc asking question, accepting any
+// of answers regardless of case and surrounding space.
+func QuestionChallenge(question string, answers ...string) ChallengeFunc {
+	return func(groupID, userID int64) Challenge {
+		return Challenge{
+			Prompt: question,
+			Check: func(answer string) bool {
+				answer = strings.TrimSpace(answer)
+				for _, a := range answers {
+					if strings.EqualFold(answer, strings.TrimSpace(a)) {
+						return true
+					}
+				}
+				return false
+			},
+		}
+	}
+}
+
+// VerifyFailAction is what MemberVerifier does to members who fail.
+type VerifyFailAction int
+
+const (
+	VerifyKick VerifyFailAction = iota
+	VerifyMute
+	VerifyNothing
+)
+
+// DefaultVerifyMuteDuration is how long VerifyMute mutes members when
+// MemberVerifier.MuteDuration is zero.
+const DefaultVerifyMuteDuration = 10 * time.Minute
+
+// Verification is a pending or finished member verification.
+type Verification struct {
+	GroupID   int64
+	UserID    int64
+	Challenge Challenge
+	Deadline  time.Time
+	Attempts  int // wrong answers so far
+
+	prompted time.Time // when the challenge was last sent
+	timer    *time.Timer
+}
+
+// verificationKey identifies the verification of a member in a group.
+type verificationKey struct {
+	groupID, userID int64
+}
+
+// MemberVerifier challenges members joining a group and kicks or mutes them
+// when they do not answer correctly in time. Pending verifications are kept
+// across reconnects; members prompted before a disconnect are prompted again
+// on the next Ready.
+type MemberVerifier struct {
+	// Returns the challenge for each new member, ArithmeticChallenge when nil.
+	Challenge ChallengeFunc
+
+	// How long members have to answer and how many wrong answers they may
+	// give before failing.
+	Timeout     time.Duration
+	MaxAttempts int
+
+	// Action on failure. MuteDuration is used with VerifyMute, zero is
+	// DefaultVerifyMuteDuration.
+	FailAction   VerifyFailAction
+	MuteDuration time.Duration
+
+	// Only verify members of these groups, all groups when empty.
+	Groups []int64
+
+	// Sent to members who pass, nothing when empty.
+	PassText string
+
+	// Called when a member passes or fails, after the fail action.
+	OnPass func(s *Session, v *Verification)
+	OnFail func(s *Session, v *Verification, reason string)
+
+	mu           sync.Mutex
+	pending      map[verificationKey]*Verification
+	disconnected time.Time // when the session last lost its connection
+}
+
+// NewMemberVerifier returns a MemberVerifier giving members 2 minutes and 3
+// attempts to solve an arithmetic challenge before kicking them.
+func NewMemberVerifier() *MemberVerifier {
+	return &MemberVerifier{
+		Challenge:   ArithmeticChallenge,
+		Timeout:     2 * time.Minute,
+		MaxAttempts: 3,
+		FailAction:  VerifyKick,
+		pending:     map[verificationKey]*Verification{},
+	}
+}
+
+// Attach verifies the members joining groups of s. It returns a function
+// that detaches it again; pending verifications keep running.
+func (mv *MemberVerifier) Attach(s *Session) func() {
+	removers := []func(){
+		s.AddHandler(mv.onJoin),
+		s.AddHandler(mv.onMessage),
+		s.AddHandler(mv.onLeave),
+		s.AddHandler(mv.onReady),
+		s.AddHandler(mv.onDisconnect),
+	}
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
+}
+
+// Pending returns copies of the verifications in progress.
+func (mv *MemberVerifier) Pending() []*Verification {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	pending := make([]*Verification, 0, len(mv.pending))
+	for _, v := range mv.pending {
+		c := *v
+		c.timer = nil
+		pending = append(pending, &c)
+	}
+	return pending
+}
+
+func (mv *MemberVerifier) timeout() time.Duration {
+	if mv.Timeout > 0 {
+		return mv.Timeout
+	}
+	return 2 * time.Minute
+}
+
+func (mv *MemberVerifier) muteDuration() time.Duration {
+	if mv.MuteDuration > 0 {
+		return mv.MuteDuration
+	}
+	return DefaultVerifyMuteDuration
+}
+
+func (mv *MemberVerifier) watches(groupID int64) bool {
+	if len(mv.Groups) == 0 {
+		return true
+	}
+	for _, id := range mv.Groups {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+func (mv *MemberVerifier) onJoin(s *Session, e *GroupMemberIncrease) {
+	if !mv.watches(e.GroupID) || s.isSelf(0, e.UserID) {
+		return
+	}
+	challenge := mv.Challenge
+	if challenge == nil {
+		challenge = ArithmeticChallenge
+	}
+	v := &Verification{
+		GroupID:   e.GroupID,
+		UserID:    e.UserID,
+		Challenge: challenge(e.GroupID, e.UserID),
+		Deadline:  time.Now().Add(mv.timeout()),
+	}
+	key := verificationKey{e.GroupID, e.UserID}
+
+	mv.mu.Lock()
+	if mv.pending == nil {
+		mv.pending = map[verificationKey]*Verification{}
+	}
+	if old := mv.pending[key]; old != nil {
+		old.timer.Stop()
+	}
+	mv.pending[key] = v
+	v.timer = time.AfterFunc(mv.timeout(), func() { mv.fail(s, key, v, "timed out") })
+	mv.mu.Unlock()
+
+	mv.prompt(s, v)
+}
+
+func (mv *MemberVerifier) onMessage(s *Session, m *ReceiveMessage) {
+	if m.MessageScene != SceneGroup {
+		return
+	}
+	key := verificationKey{m.PeerId, m.SenderId}
+	mv.mu.Lock()
+	v := mv.pending[key]
+	mv.mu.Unlock()
+	if v == nil {
+		return
+	}
+
+	// Images, stickers and other messages without text are not answers.
+	var answer strings.Builder
+	hasText := false
+	for _, segment := range m.Segments {
+		if t, ok := segment.(*TextElement); ok {
+			answer.WriteString(t.Text)
+			hasText = true
+		}
+	}
+	if !hasText {
+		return
+	}
+	if v.Challenge.Check(answer.String()) {
+		if !mv.finish(key, v) {
+			return
+		}
+		s.log().With("group_id", v.GroupID, "user_id", v.UserID).Infof("member verification passed")
+		if mv.PassText != "" {
+			message := []IMessageElement{&AtElement{UserID: v.UserID}, &TextElement{Text: " " + mv.PassText}}
+			if _, err := s.SendGroupMessage(v.GroupID, &message); err != nil {
+				s.log().Warnf("sending verification result, %s", err)
+			}
+		}
+		if mv.OnPass != nil {
+			mv.OnPass(s, v)
+		}
+		return
+	}
+
+	mv.mu.Lock()
+	v.Attempts++
+	attempts := v.Attempts
+	mv.mu.Unlock()
+	if mv.MaxAttempts > 0 && attempts >= mv.MaxAttempts {
+		mv.fail(s, key, v, "too many wrong answers")
+	}
+}
+
+func (mv *MemberVerifier) onLeave(s *Session, e *GroupMemberDecrease) {
+	key := verificationKey{e.GroupID, e.UserID}
+	mv.mu.Lock()
+	v := mv.pending[key]
+	mv.mu.Unlock()
+	if v != nil {
+		mv.finish(key, v)
+	}
+}
+
+func (mv *MemberVerifier) onDisconnect(s *Session, d *Disconnect) {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	if d.Time.After(mv.disconnected) {
+		mv.disconnected = d.Time
+	}
+}
+
+// onReady prompts the members again whose challenge was sent before the
+// last disconnect, as their answers may have been missed. Members already
+// prompted since and members with less than a second left are skipped.
+func (mv *MemberVerifier) onReady(s *Session, r *Ready) {
+	mv.mu.Lock()
+	var reprompt []*Verification
+	for _, v := range mv.pending {
+		if v.prompted.Before(mv.disconnected) && time.Until(v.Deadline) >= time.Second {
+			reprompt = append(reprompt, v)
+		}
+	}
+	mv.mu.Unlock()
+
+	for _, v := range reprompt {
+		mv.prompt(s, v)
+	}
+}
+
+func (mv *MemberVerifier) prompt(s *Session, v *Verification) {
+	mv.mu.Lock()
+	v.prompted = time.Now()
+	remaining := time.Until(v.Deadline).Round(time.Second)
+	mv.mu.Unlock()
+
+	text := fmt.Sprintf(" please answer within %s to stay in the group: %s", remaining, v.Challenge.Prompt)
+	message := []IMessageElement{&AtElement{UserID: v.UserID}, &TextElement{Text: text}}
+	if _, err := s.SendGroupMessage(v.GroupID, &message); err != nil {
+		s.log().With("group_id", v.GroupID, "user_id", v.UserID).Errorf("sending verification challenge, %s", err)
+	}
+}
+
+// finish removes v from the pending verifications. It returns false when v
+// was already finished.
+func (mv *MemberVerifier) finish(key verificationKey, v *Verification) bool {
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	if mv.pending[key] != v {
+		return false
+	}
+	delete(mv.pending, key)
+	v.timer.Stop()
+	return true
+}
+
+func (mv *MemberVerifier) fail(s *Session, key verificationKey, v *Verification, reason string) {
+	if !mv.finish(key, v) {
+		return
+	}
+	log := s.log().With("group_id", v.GroupID, "user_id", v.UserID)
+	log.Infof("member verification failed, %s", reason)
+
+	var err error
+	switch mv.FailAction {
+	case VerifyKick:
+		err = s.KickGroupMember(v.GroupID, v.UserID, false)
+	case VerifyMute:
+		err = s.SetGroupMemberMuteDuration(v.GroupID, v.UserID, mv.muteDuration())
+	}
+	if err != nil {
+		log.Errorf("acting on failed verification, %s", err)
+	}
+	if mv.OnFail != nil {
+		mv.OnFail(s, v, reason)
+	}
+}
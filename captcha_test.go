@@ -0,0 +1,153 @@
+package Milky_go_sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemberVerifier(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	kicked := make(chan int64, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := strings.TrimPrefix(r.URL.Path, "/")
+		var req struct {
+			UserID int64 `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		calls = append(calls, endpoint)
+		mu.Unlock()
+		if endpoint == EndpointKickGroupMember {
+			kicked <- req.UserID
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{}}`))
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.SyncEvents = true
+
+	mv := NewMemberVerifier()
+	mv.Challenge = QuestionChallenge("what colour is the sky?", "blue")
+	mv.MaxAttempts = 2
+	var passed []int64
+	mv.OnPass = func(s *Session, v *Verification) { passed = append(passed, v.UserID) }
+	mv.Attach(s)
+
+	answer := func(user int64, text string) {
+		s.handleEvent(messageReceiveEventType, &ReceiveMessage{
+			MessageScene: SceneGroup, PeerId: 1, SenderId: user,
+			Segments: []IMessageElement{&AtElement{UserID: 99}, &TextElement{Text: text}},
+		})
+	}
+
+	s.handleEvent(groupMemberIncreaseEventType, &GroupMemberIncrease{GroupID: 1, UserID: 2})
+	answer(2, "green")
+	answer(2, " Blue ")
+	if len(passed) != 1 || len(mv.Pending()) != 0 {
+		t.Fatalf("passed %v, pending %d", passed, len(mv.Pending()))
+	}
+
+	// A pending challenge is asked again after a reconnect, but only once.
+	s.handleEvent(groupMemberIncreaseEventType, &GroupMemberIncrease{GroupID: 1, UserID: 3})
+	s.handleEvent(readyEventType, &Ready{})
+	s.handleEvent(disconnectEventType, &Disconnect{Time: time.Now()})
+	s.handleEvent(readyEventType, &Ready{})
+	s.handleEvent(readyEventType, &Ready{})
+	// A member whose time is up is not prompted again.
+	s.handleEvent(groupMemberIncreaseEventType, &GroupMemberIncrease{GroupID: 1, UserID: 4})
+	mv.mu.Lock()
+	mv.pending[verificationKey{1, 4}].Deadline = time.Now()
+	mv.mu.Unlock()
+	s.handleEvent(disconnectEventType, &Disconnect{Time: time.Now()})
+	s.handleEvent(readyEventType, &Ready{})
+	s.handleEvent(groupMemberDecreaseEventType, &GroupMemberDecrease{GroupID: 1, UserID: 4})
+
+	// Messages without text are not counted as answers.
+	s.handleEvent(messageReceiveEventType, &ReceiveMessage{
+		MessageScene: SceneGroup, PeerId: 1, SenderId: 3,
+		Segments: []IMessageElement{&ImageElement{}},
+	})
+	answer(3, "red")
+	pending := mv.Pending()
+	if len(pending) != 1 || pending[0].UserID != 3 || pending[0].Attempts != 1 {
+		t.Fatalf("unexpected pending verifications %+v", pending)
+	}
+	// Pending hands out copies, changing them does not affect the verifier.
+	pending[0].Attempts = 0
+	answer(3, "grey")
+	select {
+	case user := <-kicked:
+		if user != 3 {
+			t.Errorf("kicked %d, want 3", user)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("member with wrong answers was not kicked")
+	}
+
+	mu.Lock()
+	got := strings.Join(calls, ",")
+	mu.Unlock()
+	want := strings.Join([]string{
+		EndpointSendGroupMessage, // user 2
+		EndpointSendGroupMessage, // user 3
+		EndpointSendGroupMessage, // user 3 after the first disconnect
+		EndpointSendGroupMessage, // user 4
+		EndpointSendGroupMessage, // user 3 after the second disconnect, user 4 has no time left
+		EndpointKickGroupMember,
+	}, ",")
+	if got != want {
+		t.Errorf("calls %s, want %s", got, want)
+	}
+}
+
+func TestMemberVerifierTimeout(t *testing.T) {
+	muted := make(chan int64, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, EndpointSetGroupMemberMute) {
+			var req struct {
+				Duration int64 `json:"duration"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			muted <- req.Duration
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{}}`))
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.SyncEvents = true
+	mv := NewMemberVerifier()
+	mv.Timeout = 20 * time.Millisecond
+	mv.FailAction = VerifyMute
+	failed := make(chan string, 1)
+	mv.OnFail = func(s *Session, v *Verification, reason string) { failed <- reason }
+	mv.Attach(s)
+
+	s.handleEvent(groupMemberIncreaseEventType, &GroupMemberIncrease{GroupID: 1, UserID: 2})
+	select {
+	case reason := <-failed:
+		if reason != "timed out" {
+			t.Errorf("unexpected reason %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("verification did not time out")
+	}
+	// A zero MuteDuration must not unmute, it defaults to 10 minutes.
+	select {
+	case duration := <-muted:
+		if duration != int64(DefaultVerifyMuteDuration/time.Second) {
+			t.Errorf("muted for %ds, want %s", duration, DefaultVerifyMuteDuration)
+		}
+	default:
+		t.Error("member was not muted")
+	}
+}
@@ -0,0 +1,307 @@
+package Milky_go_sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrInvalidConfig is wrapped by all configuration errors.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// DefaultEnvPrefix is the prefix of the environment variables read by
+// LoadConfigFromEnv when no prefix is given.
+const DefaultEnvPrefix = "MILKY_"
+
+// Config describes a Session. It can be written by hand, loaded from the
+// environment with LoadConfigFromEnv or from JSON with LoadConfigFile.
+type Config struct {
+	// Base URL of the Milky implementation, such as http://127.0.0.1:3000.
+	// The websocket gateway is BaseURL/event and the REST gateway
+	// BaseURL/api.
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Override the gateways derived from BaseURL.
+	WSGateway   string `json:"ws_gateway,omitempty"`
+	RestGateway string `json:"rest_gateway,omitempty"`
+
+	Token string `json:"token,omitempty"`
+
+	// One of error, warning, info or debug. Empty means debug.
+	LogLevel string `json:"log_level,omitempty"`
+
+	// Retries of REST calls on bad gateway, 3 when nil.
+	MaxRestRetries *int `json:"max_rest_retries,omitempty"`
+
+	// Timeout of REST calls, 20 seconds when 0.
+	RequestTimeout ConfigDuration `json:"request_timeout,omitempty"`
+
+	SyncEvents       bool `json:"sync_events,omitempty"`
+	WSTokenInHeader  bool `json:"ws_token_in_header,omitempty"`
+	DisableReconnect bool `json:"disable_reconnect,omitempty"`
+}
+
+// ConfigDuration is a time.Duration written in JSON as a string such as
+// "20s", or as a number of seconds.
+type ConfigDuration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *ConfigDuration) UnmarshalJSON(b []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(b, &seconds); err == nil {
+		*d = ConfigDuration(seconds * float64(time.Second))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// parse reads a duration such as "20s", or a plain number of seconds.
+func (d *ConfigDuration) parse(s string) error {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		*d = ConfigDuration(seconds * float64(time.Second))
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = ConfigDuration(parsed)
+	return nil
+}
+
+// Option configures a Session created by NewWithConfig.
+type Option func(s *Session)
+
+// WithLogger sets the session's Logger.
+func WithLogger(logger Logger) Option {
+	return func(s *Session) {
+		s.Logger = logger
+	}
+}
+
+// WithHTTPClient sets the http client used for REST calls.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Session) {
+		if client != nil {
+			s.Client = client
+		}
+	}
+}
+
+// WithDialer sets the dialer used for the websocket.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(s *Session) {
+		if dialer != nil {
+			s.Dialer = dialer
+		}
+	}
+}
+
+// WithMetrics sets the session's Metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(s *Session) {
+		s.Metrics = metrics
+	}
+}
+
+// WithTracer sets the session's Tracer.
+func WithTracer(tracer Tracer) Option {
+	return func(s *Session) {
+		s.Tracer = tracer
+	}
+}
+
+// WithWorkerPool dispatches the session's events on pool.
+func WithWorkerPool(pool *WorkerPool) Option {
+	return func(s *Session) {
+		s.WorkerPool = pool
+	}
+}
+
+// NewWithConfig creates a Session from cfg, then applies opts. Unlike New it
+// validates the gateways and returns an error wrapping ErrInvalidConfig when
+// they are missing or malformed.
+func NewWithConfig(cfg Config, opts ...Option) (*Session, error) {
+	wsGateway, restGateway, err := cfg.Gateways()
+	if err != nil {
+		return nil, err
+	}
+	level := LogDebug
+	if cfg.LogLevel != "" {
+		if level, err = ParseLogLevel(cfg.LogLevel); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+	}
+
+	s, err := New(wsGateway, restGateway, cfg.Token, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.LogLevel = level
+	if cfg.MaxRestRetries != nil {
+		s.MaxRestRetries = *cfg.MaxRestRetries
+	}
+	if cfg.RequestTimeout > 0 {
+		s.Client = &http.Client{Timeout: time.Duration(cfg.RequestTimeout)}
+	}
+	s.SyncEvents = cfg.SyncEvents
+	s.WSTokenInHeader = cfg.WSTokenInHeader
+	s.ShouldReconnectOnError = !cfg.DisableReconnect
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Gateways returns the websocket and REST gateways described by cfg. Both
+// are required, either set directly or derived from BaseURL.
+func (cfg Config) Gateways() (wsGateway string, restGateway string, err error) {
+	if cfg.BaseURL != "" {
+		base, err := parseGateway(cfg.BaseURL, "base_url", "http", "https")
+		if err != nil {
+			return "", "", err
+		}
+		path := strings.TrimSuffix(base.Path, "/")
+		rest, ws := *base, *base
+		rest.Path = path + "/api"
+		ws.Path = path + "/event"
+		ws.Scheme = "ws"
+		if base.Scheme == "https" {
+			ws.Scheme = "wss"
+		}
+		wsGateway, restGateway = ws.String(), rest.String()
+	}
+	if cfg.WSGateway != "" {
+		if _, err = parseGateway(cfg.WSGateway, "ws_gateway", "ws", "wss"); err != nil {
+			return "", "", err
+		}
+		wsGateway = cfg.WSGateway
+	}
+	if cfg.RestGateway != "" {
+		if _, err = parseGateway(cfg.RestGateway, "rest_gateway", "http", "https"); err != nil {
+			return "", "", err
+		}
+		restGateway = strings.TrimSuffix(cfg.RestGateway, "/")
+	}
+	if wsGateway == "" {
+		return "", "", fmt.Errorf("%w: ws_gateway is required, set it or base_url", ErrInvalidConfig)
+	}
+	if restGateway == "" {
+		return "", "", fmt.Errorf("%w: rest_gateway is required, set it or base_url", ErrInvalidConfig)
+	}
+	return wsGateway, restGateway, nil
+}
+
+func parseGateway(raw, field string, schemes ...string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, field, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: %s: %q has no host", ErrInvalidConfig, field, raw)
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			u.Scheme = scheme
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s: scheme must be %s, got %q", ErrInvalidConfig, field, strings.Join(schemes, " or "), u.Scheme)
+}
+
+// ParseLogLevel parses one of error, warning (or warn), info or debug.
+func ParseLogLevel(level string) (int, error) {
+	switch strings.ToLower(level) {
+	case "error":
+		return LogError, nil
+	case "warning", "warn":
+		return LogWarning, nil
+	case "info", "informational":
+		return LogInformational, nil
+	case "debug":
+		return LogDebug, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+// LoadConfigFromEnv reads a Config from environment variables named prefix
+// followed by the upper cased JSON field name, such as MILKY_BASE_URL and
+// MILKY_TOKEN. DefaultEnvPrefix is used when prefix is empty.
+func LoadConfigFromEnv(prefix string) (Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	env := func(name string) (string, bool) {
+		return os.LookupEnv(prefix + name)
+	}
+
+	var cfg Config
+	cfg.BaseURL, _ = env("BASE_URL")
+	cfg.WSGateway, _ = env("WS_GATEWAY")
+	cfg.RestGateway, _ = env("REST_GATEWAY")
+	cfg.Token, _ = env("TOKEN")
+	cfg.LogLevel, _ = env("LOG_LEVEL")
+
+	if v, ok := env("MAX_REST_RETRIES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("%w: %sMAX_REST_RETRIES: %s", ErrInvalidConfig, prefix, err)
+		}
+		cfg.MaxRestRetries = &n
+	}
+	if v, ok := env("REQUEST_TIMEOUT"); ok {
+		if err := cfg.RequestTimeout.parse(v); err != nil {
+			return cfg, fmt.Errorf("%w: %sREQUEST_TIMEOUT: %s", ErrInvalidConfig, prefix, err)
+		}
+	}
+	for name, field := range map[string]*bool{
+		"SYNC_EVENTS":        &cfg.SyncEvents,
+		"WS_TOKEN_IN_HEADER": &cfg.WSTokenInHeader,
+		"DISABLE_RECONNECT":  &cfg.DisableReconnect,
+	} {
+		if v, ok := env(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%w: %s%s: %s", ErrInvalidConfig, prefix, name, err)
+			}
+			*field = b
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfigFile reads a Config from a JSON file. Unknown fields are an
+// error, so typos are not silently ignored.
+func LoadConfigFile(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, path, err)
+	}
+	return cfg, nil
+}
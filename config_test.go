@@ -0,0 +1,110 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigGateways(t *testing.T) {
+	for _, tc := range []struct {
+		cfg       Config
+		ws, rest  string
+		wantError bool
+	}{
+		{cfg: Config{BaseURL: "http://127.0.0.1:3000"}, ws: "ws://127.0.0.1:3000/event", rest: "http://127.0.0.1:3000/api"},
+		{cfg: Config{BaseURL: "HTTPS://bot.example.com/milky/"}, ws: "wss://bot.example.com/milky/event", rest: "https://bot.example.com/milky/api"},
+		{cfg: Config{BaseURL: "http://host", WSGateway: "ws://other/ws"}, ws: "ws://other/ws", rest: "http://host/api"},
+		{cfg: Config{WSGateway: "ws://host/event", RestGateway: "http://host/api/"}, ws: "ws://host/event", rest: "http://host/api"},
+		{cfg: Config{RestGateway: "http://host/api"}, wantError: true},
+		{cfg: Config{WSGateway: "ws://host/event"}, wantError: true},
+		{cfg: Config{BaseURL: "ws://host"}, wantError: true},
+		{cfg: Config{WSGateway: "http://host/event"}, wantError: true},
+		{cfg: Config{RestGateway: "host:3000/api"}, wantError: true},
+		{cfg: Config{}, wantError: true},
+	} {
+		ws, rest, err := tc.cfg.Gateways()
+		if tc.wantError {
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("%+v: expected ErrInvalidConfig, got %v", tc.cfg, err)
+			}
+			continue
+		}
+		if err != nil || ws != tc.ws || rest != tc.rest {
+			t.Errorf("%+v: got %q, %q, %v; want %q, %q", tc.cfg, ws, rest, err, tc.ws, tc.rest)
+		}
+	}
+}
+
+func TestNewWithConfig(t *testing.T) {
+	retries := 0
+	client := &http.Client{}
+	s, err := NewWithConfig(Config{
+		BaseURL:          "http://host:3000",
+		Token:            "t",
+		LogLevel:         "warn",
+		MaxRestRetries:   &retries,
+		SyncEvents:       true,
+		DisableReconnect: true,
+	}, WithHTTPClient(client), WithLogger(&TestLogger{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.WSGateway != "ws://host:3000/event" || s.endpoint(EndpointGetLoginInfo) != "http://host:3000/api/get_login_info" {
+		t.Errorf("unexpected gateways %q, %q", s.WSGateway, s.endpoint(EndpointGetLoginInfo))
+	}
+	if s.LogLevel != LogWarning || s.MaxRestRetries != 0 || !s.SyncEvents || s.ShouldReconnectOnError || s.Client != client || s.Logger == nil {
+		t.Errorf("config not applied: %+v", s)
+	}
+
+	if _, err = NewWithConfig(Config{BaseURL: "http://host", LogLevel: "loud"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for a bad log level, got %v", err)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("BOT_BASE_URL", "http://host")
+	t.Setenv("BOT_TOKEN", "secret")
+	t.Setenv("BOT_MAX_REST_RETRIES", "5")
+	t.Setenv("BOT_REQUEST_TIMEOUT", "1m")
+	t.Setenv("BOT_WS_TOKEN_IN_HEADER", "true")
+
+	cfg, err := LoadConfigFromEnv("BOT_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "http://host" || cfg.Token != "secret" || cfg.MaxRestRetries == nil || *cfg.MaxRestRetries != 5 ||
+		time.Duration(cfg.RequestTimeout) != time.Minute || !cfg.WSTokenInHeader {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv("BOT_SYNC_EVENTS", "maybe")
+	if _, err = LoadConfigFromEnv("BOT_"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "milky.json")
+	if err := os.WriteFile(path, []byte(`{"base_url":"https://host","request_timeout":30,"log_level":"info"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BaseURL != "https://host" || time.Duration(cfg.RequestTimeout) != 30*time.Second || cfg.LogLevel != "info" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"base_uri":"https://host"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadConfigFile(path); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected ErrInvalidConfig for an unknown field, got %v", err)
+	}
+}
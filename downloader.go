@@ -0,0 +1,348 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// All downloader error constants
+var (
+	ErrNotResourceElement   = errors.New("message element does not carry a downloadable resource")
+	ErrResourceTooLarge     = errors.New("resource exceeds the maximum download size")
+	ErrResourceSizeMismatch = errors.New("downloaded resource size does not match Content-Length")
+	ErrNoDownloadDir        = errors.New("no download directory set, cannot store resource")
+	ErrNoDownloaderSession  = errors.New("no session set, cannot refresh resource temp url")
+)
+
+// defaultMaxConcurrentDownloads is used when NewMediaDownloader is given a
+// non-positive concurrency limit.
+const defaultMaxConcurrentDownloads = 4
+
+// ResourceOf returns the resource ID and temporary URL carried by an incoming
+// image, record or video element.
+func ResourceOf(element IMessageElement) (resourceID string, tempURL string, ok bool) {
+	switch e := element.(type) {
+	case *ImageElement:
+		return e.ResourceID, e.TempURL, e.ResourceID != "" || e.TempURL != ""
+	case *RecordElement:
+		return e.ResourceID, e.TempURL, e.ResourceID != "" || e.TempURL != ""
+	case *VideoElement:
+		return e.ResourceID, e.TempURL, e.ResourceID != "" || e.TempURL != ""
+	default:
+		return "", "", false
+	}
+}
+
+// StoredResource describes a resource written to the content-addressed store.
+type StoredResource struct {
+	ResourceID string
+	Hash       string // hex encoded sha256 of the content
+	Path       string
+	Size       int64
+	Existed    bool // true if the content was already present in the store
+}
+
+// MediaDownloader downloads incoming media resources, refreshing expired
+// temporary URLs through GetResourceTempURL.
+type MediaDownloader struct {
+	Session *Session
+
+	// The http client used to fetch resource content. Resource downloads
+	// are bounded by the request context rather than a client timeout.
+	Client *http.Client
+
+	// Root directory of the content-addressed store used by Save.
+	Dir string
+
+	// Maximum accepted resource size in bytes, 0 means no limit.
+	MaxSize int64
+
+	sem     chan struct{}
+	semOnce sync.Once
+
+	// serialises renames into the store so duplicate content is only kept once
+	storeMu sync.Mutex
+}
+
+// NewMediaDownloader returns a MediaDownloader storing into dir that runs at
+// most maxConcurrent downloads at the same time.
+func NewMediaDownloader(s *Session, dir string, maxConcurrent int) *MediaDownloader {
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentDownloads
+	}
+	return &MediaDownloader{
+		Session: s,
+		Client:  &http.Client{},
+		Dir:     dir,
+		MaxSize: maxFileSize,
+		sem:     make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Download streams the resource carried by element to w.
+func (d *MediaDownloader) Download(ctx context.Context, element IMessageElement, w io.Writer) (int64, error) {
+	resourceID, tempURL, ok := ResourceOf(element)
+	if !ok {
+		return 0, ErrNotResourceElement
+	}
+	return d.DownloadResource(ctx, resourceID, tempURL, w)
+}
+
+// DownloadResource streams a resource to w. tempURL may be empty, in which
+// case a fresh URL is requested for resourceID.
+func (d *MediaDownloader) DownloadResource(ctx context.Context, resourceID string, tempURL string, w io.Writer) (int64, error) {
+	sem := d.semaphore()
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	defer func() { <-sem }()
+
+	refreshed := false
+	if tempURL == "" {
+		if resourceID == "" {
+			return 0, ErrNotResourceElement
+		}
+		var err error
+		if tempURL, err = d.refresh(ctx, resourceID); err != nil {
+			return 0, err
+		}
+		refreshed = true
+	}
+
+	for {
+		resp, err := d.get(ctx, tempURL)
+		if err != nil {
+			return 0, err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			n, err := d.copy(w, resp)
+			_ = resp.Body.Close()
+			return n, err
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if refreshed || resourceID == "" || !isExpiredURLStatus(resp.StatusCode) {
+			return 0, fmt.Errorf("downloading resource %s: HTTP %s", resourceID, resp.Status)
+		}
+
+		if d.Session != nil {
+			d.Session.log().Debugf("temp url for resource %s expired (%s), refreshing", resourceID, resp.Status)
+		}
+		if tempURL, err = d.refresh(ctx, resourceID); err != nil {
+			return 0, err
+		}
+		refreshed = true
+	}
+}
+
+// Save downloads the resource carried by element into the content-addressed
+// store under Dir. Content that is already stored is not written twice.
+func (d *MediaDownloader) Save(ctx context.Context, element IMessageElement) (*StoredResource, error) {
+	if d.Dir == "" {
+		return nil, ErrNoDownloadDir
+	}
+	resourceID, tempURL, ok := ResourceOf(element)
+	if !ok {
+		return nil, ErrNotResourceElement
+	}
+	if err := os.MkdirAll(d.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(d.Dir, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	h := sha256.New()
+	n, err := d.DownloadResource(ctx, resourceID, tempURL, io.MultiWriter(tmp, h))
+	if err != nil {
+		return nil, err
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	return d.commit(tmp.Name(), resourceID, h, n)
+}
+
+// SaveToFile downloads the resource carried by element to path.
+func (d *MediaDownloader) SaveToFile(ctx context.Context, element IMessageElement, path string) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := d.Download(ctx, element, f)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return n, err
+}
+
+// SaveAll stores every resource carried by segments, running downloads in
+// parallel up to the downloader's concurrency limit. Results are in segment
+// order; non-resource segments are skipped. The first error is returned
+// alongside whatever was stored successfully.
+func (d *MediaDownloader) SaveAll(ctx context.Context, segments []IMessageElement) ([]*StoredResource, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	results := make([]*StoredResource, len(segments))
+	for i, segment := range segments {
+		if _, _, ok := ResourceOf(segment); !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, segment IMessageElement) {
+			defer wg.Done()
+			stored, err := d.Save(ctx, segment)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			results[i] = stored
+		}(i, segment)
+	}
+	wg.Wait()
+
+	stored := make([]*StoredResource, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			stored = append(stored, r)
+		}
+	}
+	return stored, firstErr
+}
+
+// semaphore returns the channel bounding concurrent downloads. A
+// MediaDownloader built without NewMediaDownloader gets the default limit.
+func (d *MediaDownloader) semaphore() chan struct{} {
+	d.semOnce.Do(func() {
+		if d.sem == nil {
+			d.sem = make(chan struct{}, defaultMaxConcurrentDownloads)
+		}
+	})
+	return d.sem
+}
+
+// PathForHash returns where content with the given hex sha256 is kept in the store.
+func (d *MediaDownloader) PathForHash(sum string) string {
+	if len(sum) < 2 {
+		return filepath.Join(d.Dir, sum)
+	}
+	return filepath.Join(d.Dir, sum[:2], sum)
+}
+
+func (d *MediaDownloader) refresh(ctx context.Context, resourceID string) (string, error) {
+	if d.Session == nil {
+		return "", ErrNoDownloaderSession
+	}
+	url, err := d.Session.GetResourceTempURL(resourceID, WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("refreshing temp url for resource %s: %w", resourceID, err)
+	}
+	return url, nil
+}
+
+func (d *MediaDownloader) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.Session != nil && d.Session.UserAgent != "" {
+		req.Header.Set("User-Agent", d.Session.UserAgent)
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// copy streams the response body to w, enforcing MaxSize and checking the
+// number of bytes received against Content-Length.
+func (d *MediaDownloader) copy(w io.Writer, resp *http.Response) (int64, error) {
+	if d.MaxSize > 0 && resp.ContentLength > d.MaxSize {
+		return 0, ErrResourceTooLarge
+	}
+
+	var body io.Reader = resp.Body
+	if d.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, d.MaxSize+1)
+	}
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, err
+	}
+	if d.MaxSize > 0 && n > d.MaxSize {
+		return n, ErrResourceTooLarge
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return n, fmt.Errorf("%w: got %d bytes, expected %d", ErrResourceSizeMismatch, n, resp.ContentLength)
+	}
+	return n, nil
+}
+
+// commit moves a fully downloaded temporary file to its content address.
+func (d *MediaDownloader) commit(tmpPath string, resourceID string, h hash.Hash, size int64) (*StoredResource, error) {
+	sum := hex.EncodeToString(h.Sum(nil))
+	stored := &StoredResource{
+		ResourceID: resourceID,
+		Hash:       sum,
+		Path:       d.PathForHash(sum),
+		Size:       size,
+	}
+
+	d.storeMu.Lock()
+	defer d.storeMu.Unlock()
+
+	if _, err := os.Stat(stored.Path); err == nil {
+		stored.Existed = true
+		return stored, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(stored.Path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, stored.Path); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// isExpiredURLStatus reports whether an HTTP status from a temp URL means the
+// URL should be refreshed and the download retried.
+func isExpiredURLStatus(code int) bool {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package Milky_go_sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMediaDownloaderRefreshesExpiredURL(t *testing.T) {
+	content := []byte("fake image content")
+
+	media := http.NewServeMux()
+	media.HandleFunc("/expired", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	media.HandleFunc("/fresh", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	})
+	mediaServer := httptest.NewServer(media)
+	defer mediaServer.Close()
+
+	refreshes := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+EndpointGetResourceTempURL {
+			http.NotFound(w, r)
+			return
+		}
+		refreshes++
+		data, _ := json.Marshal(map[string]string{"url": mediaServer.URL + "/fresh"})
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: data})
+	}))
+	defer api.Close()
+
+	s, _ := New("", api.URL, "", &TestLogger{})
+	d := NewMediaDownloader(s, t.TempDir(), 2)
+
+	element := &ImageElement{ResourceID: "res-1", TempURL: mediaServer.URL + "/expired"}
+	var buf bytes.Buffer
+	n, err := d.Download(context.Background(), element, &buf)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Fatalf("Download wrote %q, want %q", buf.Bytes(), content)
+	}
+	if refreshes != 1 {
+		t.Fatalf("expected 1 temp url refresh, got %d", refreshes)
+	}
+
+	first, err := d.Save(context.Background(), element)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	second, err := d.Save(context.Background(), &VideoElement{ResourceID: "res-2", TempURL: mediaServer.URL + "/fresh"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if first.Existed || !second.Existed || first.Path != second.Path {
+		t.Fatalf("expected identical content to be deduplicated, got %+v and %+v", first, second)
+	}
+	stored, err := os.ReadFile(first.Path)
+	if err != nil || !bytes.Equal(stored, content) {
+		t.Fatalf("stored content %q (%v), want %q", stored, err, content)
+	}
+
+	d.MaxSize = 4
+	if _, err = d.Download(context.Background(), element, &buf); !errors.Is(err, ErrResourceTooLarge) {
+		t.Fatalf("expected ErrResourceTooLarge, got %v", err)
+	}
+
+	if _, err = d.Download(context.Background(), &TextElement{Text: "hi"}, &buf); !errors.Is(err, ErrNotResourceElement) {
+		t.Fatalf("expected ErrNotResourceElement, got %v", err)
+	}
+}
+
+func TestMediaDownloaderWithoutSession(t *testing.T) {
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer media.Close()
+
+	// A MediaDownloader literal must not block on its download semaphore.
+	d := &MediaDownloader{}
+
+	var buf bytes.Buffer
+	if _, err := d.DownloadResource(context.Background(), "res-1", "", &buf); !errors.Is(err, ErrNoDownloaderSession) {
+		t.Fatalf("expected ErrNoDownloaderSession, got %v", err)
+	}
+	if _, err := d.DownloadResource(context.Background(), "res-1", media.URL, &buf); !errors.Is(err, ErrNoDownloaderSession) {
+		t.Fatalf("expected ErrNoDownloaderSession for an expired url, got %v", err)
+	}
+}
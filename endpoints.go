@@ -16,6 +16,27 @@ func (e *apiEndpoints) Endpoint(path string) string {
 	return fmt.Sprintf("%s/%s", e.Gateway, path)
 }
 
+// endpoint returns the URL of an API endpoint on the current REST gateway.
+func (s *Session) endpoint(path string) string {
+	s.gatewayMu.RLock()
+	defer s.gatewayMu.RUnlock()
+	return s.apiEndpoints.Endpoint(path)
+}
+
+// setGateways points the session at another pair of gateways. The websocket
+// is not reconnected.
+func (s *Session) setGateways(wsGateway, restGateway string) {
+	s.Lock()
+	s.WSGateway = wsGateway
+	s.RestGateway = restGateway
+	s.Unlock()
+
+	s.gatewayMu.Lock()
+	s.apiEndpoints = newAPIEndpoints(restGateway)
+	s.capabilities = nil
+	s.gatewayMu.Unlock()
+}
+
 const (
 	// EndpointGetLoginInfo System
 
@@ -0,0 +1,125 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEnum is wrapped by the errors of API calls given an enum value
+// the protocol does not define.
+var ErrInvalidEnum = errors.New("invalid enum value")
+
+// MessageScene is the kind of conversation a message belongs to.
+type MessageScene string
+
+const (
+	SceneFriend MessageScene = "friend"
+	SceneGroup  MessageScene = "group"
+	SceneTemp   MessageScene = "temp"
+)
+
+// IsValid reports whether m is one of the scenes defined by the protocol.
+func (m MessageScene) IsValid() bool {
+	switch m {
+	case SceneFriend, SceneGroup, SceneTemp:
+		return true
+	}
+	return false
+}
+
+// Role is the role of a group member.
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+)
+
+// IsValid reports whether r is one of the roles defined by the protocol.
+func (r Role) IsValid() bool {
+	return r.rank() > 0
+}
+
+// CanManage reports whether a member with role r can manage, such as mute
+// or kick, a member with role other. Only a higher role can manage a lower
+// one, so admins cannot manage each other.
+func (r Role) CanManage(other Role) bool {
+	return other.IsValid() && r.rank() > other.rank()
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleMember:
+		return 1
+	}
+	return 0
+}
+
+// Sex is the sex of a user as shown on their profile.
+type Sex string
+
+const (
+	SexMale    Sex = "male"
+	SexFemale  Sex = "female"
+	SexUnknown Sex = "unknown"
+)
+
+// IsValid reports whether x is one of the values defined by the protocol.
+func (x Sex) IsValid() bool {
+	switch x {
+	case SexMale, SexFemale, SexUnknown:
+		return true
+	}
+	return false
+}
+
+// RequestState is the state of a group request or notification.
+type RequestState string
+
+const (
+	RequestPending  RequestState = "pending"
+	RequestAccepted RequestState = "accepted"
+	RequestRejected RequestState = "rejected"
+	RequestIgnored  RequestState = "ignored"
+)
+
+// IsValid reports whether r is one of the states defined by the protocol.
+func (r RequestState) IsValid() bool {
+	switch r {
+	case RequestPending, RequestAccepted, RequestRejected, RequestIgnored:
+		return true
+	}
+	return false
+}
+
+// ImageSubType is the kind of an image segment.
+type ImageSubType string
+
+const (
+	ImageNormal  ImageSubType = "normal"
+	ImageSticker ImageSubType = "sticker"
+)
+
+// IsValid reports whether t is one of the image sub types defined by the
+// protocol.
+func (t ImageSubType) IsValid() bool {
+	switch t {
+	case ImageNormal, ImageSticker:
+		return true
+	}
+	return false
+}
+
+// validMessageScene returns an error wrapping ErrInvalidEnum for scenes the
+// protocol does not define.
+func validMessageScene(m MessageScene) error {
+	if !m.IsValid() {
+		return fmt.Errorf("%w: message scene %q", ErrInvalidEnum, m)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package Milky_go_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRoleCanManage(t *testing.T) {
+	for _, tc := range []struct {
+		r, other Role
+		want     bool
+	}{
+		{RoleOwner, RoleAdmin, true},
+		{RoleOwner, RoleMember, true},
+		{RoleAdmin, RoleMember, true},
+		{RoleAdmin, RoleAdmin, false},
+		{RoleAdmin, RoleOwner, false},
+		{RoleMember, RoleMember, false},
+		{RoleOwner, Role("Admin"), false},
+		{Role(""), RoleMember, false},
+	} {
+		if got := tc.r.CanManage(tc.other); got != tc.want {
+			t.Errorf("%q.CanManage(%q) = %v, want %v", tc.r, tc.other, got, tc.want)
+		}
+	}
+}
+
+func TestEnumIsValid(t *testing.T) {
+	if !SceneTemp.IsValid() || MessageScene("groups").IsValid() {
+		t.Error("MessageScene.IsValid")
+	}
+	if !SexUnknown.IsValid() || Sex("").IsValid() {
+		t.Error("Sex.IsValid")
+	}
+	if !RequestIgnored.IsValid() || RequestState("done").IsValid() {
+		t.Error("RequestState.IsValid")
+	}
+	if !ImageSticker.IsValid() || ImageSubType("gif").IsValid() {
+		t.Error("ImageSubType.IsValid")
+	}
+
+	var member GroupMemberInfo
+	if err := json.Unmarshal([]byte(`{"role":"admin","sex":"female"}`), &member); err != nil {
+		t.Fatal(err)
+	}
+	if member.Role != RoleAdmin || member.Sex != SexFemale {
+		t.Errorf("unexpected member %+v", member)
+	}
+}
+
+func TestInvalidMessageScene(t *testing.T) {
+	s, _ := New("", "http://127.0.0.1:1", "", nil)
+	if _, err := s.GetMessage("groups", 1, 1); !errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("GetMessage: expected ErrInvalidEnum, got %v", err)
+	}
+	if _, _, err := s.GetHistoryMessages("", 1, 0, 10); !errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("GetHistoryMessages: expected ErrInvalidEnum, got %v", err)
+	}
+	if err := s.MarkMessageAsRead("Friend", 1, 1); !errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("MarkMessageAsRead: expected ErrInvalidEnum, got %v", err)
+	}
+}
+
+func TestInvalidImageSubType(t *testing.T) {
+	s, _ := New("", "http://127.0.0.1:1", "", nil)
+	message := []IMessageElement{&ImageElement{URI: "file:///a.png", SubType: "gif"}}
+	if _, err := s.SendGroupMessage(1, &message); !errors.Is(err, ErrInvalidEnum) {
+		t.Errorf("SendGroupMessage: expected ErrInvalidEnum, got %v", err)
+	}
+	if _, err := json.Marshal(&ImageElement{URI: "file:///a.png"}); err != nil {
+		t.Errorf("an empty sub type is left to the implementation, got %v", err)
+	}
+}
@@ -1,5 +1,13 @@
 package Milky_go_sdk
 
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
 // EventHandler is an interface for events.
 type EventHandler interface {
 	// Type returns the type of event this handler belongs to.
@@ -59,6 +67,26 @@ func registerInterfaceProvider(eh EventInterfaceProvider) {
 	return
 }
 
+// internalInterfaceProviders holds the events the SDK emits itself. They are
+// kept apart so they cannot be received from the gateway.
+var internalInterfaceProviders = map[string]EventInterfaceProvider{}
+
+func registerInternalEvent(eh EventInterfaceProvider) {
+	internalInterfaceProviders[eh.Type()] = eh
+}
+
+// eventProviders returns the providers of all events, received or internal.
+func eventProviders() map[string]EventInterfaceProvider {
+	providers := make(map[string]EventInterfaceProvider, len(registeredInterfaceProviders)+len(internalInterfaceProviders))
+	for t, p := range registeredInterfaceProviders {
+		providers[t] = p
+	}
+	for t, p := range internalInterfaceProviders {
+		providers[t] = p
+	}
+	return providers
+}
+
 // addEventHandler adds an event handler that will be fired anytime
 // the WSAPI matching eventHandler.Type() fires.
 func (s *Session) addEventHandler(eventHandler EventHandler) func() {
@@ -102,7 +130,7 @@ func (s *Session) AddHandler(handler interface{}) func() {
 	eh := handlerForInterface(handler)
 
 	if eh == nil {
-		s.Logger.Errorf("Invalid handler type, handler will never be called")
+		s.log().Errorf("Invalid handler type, handler will never be called")
 		return func() {}
 	}
 
@@ -116,7 +144,7 @@ func (s *Session) AddHandlerOnce(handler interface{}) func() {
 	eh := handlerForInterface(handler)
 
 	if eh == nil {
-		s.Logger.Errorf("Invalid handler type, handler will never be called")
+		s.log().Errorf("Invalid handler type, handler will never be called")
 		return func() {}
 	}
 
@@ -144,30 +172,133 @@ func (s *Session) removeEventHandlerInstance(t string, ehi *eventHandlerInstance
 }
 
 // Handles calling permanent and once handlers for an event type.
-func (s *Session) handle(t string, i interface{}) {
+func (s *Session) handle(t string, eventType string, i interface{}) {
 	for _, eh := range s.handlers[t] {
 		if s.SyncEvents {
-			eh.eventHandler.Handle(s, i)
+			s.runHandler(eh.eventHandler, eventType, i)
 		} else {
-			go eh.eventHandler.Handle(s, i)
+			s.goHandler(eh.eventHandler, eventType, i)
 		}
 	}
 
 	if len(s.onceHandlers[t]) > 0 {
 		for _, eh := range s.onceHandlers[t] {
 			if s.SyncEvents {
-				eh.eventHandler.Handle(s, i)
+				s.runHandler(eh.eventHandler, eventType, i)
 			} else {
-				go eh.eventHandler.Handle(s, i)
+				s.goHandler(eh.eventHandler, eventType, i)
 			}
 		}
 		s.onceHandlers[t] = nil
 	}
 }
 
+// HandlerPanic describes a panic recovered from an event handler.
+type HandlerPanic struct {
+	EventType string
+	Event     interface{} // the event passed to the handler
+	Value     interface{} // the value passed to panic
+	Stack     []byte
+}
+
+// HandlerWithTimeout wraps handler, any type accepted by AddHandler, so that
+// it is reported as slow after d instead of Session.HandlerTimeout. It
+// returns nil if handler is not a valid handler.
+func HandlerWithTimeout(handler interface{}, d time.Duration) EventHandler {
+	eh := handlerForInterface(handler)
+	if eh == nil {
+		return nil
+	}
+	return timeoutEventHandler{EventHandler: eh, timeout: d}
+}
+
+type timeoutEventHandler struct {
+	EventHandler
+	timeout time.Duration
+}
+
+func (eh timeoutEventHandler) HandleContext(ctx context.Context, s *Session, i interface{}) {
+	if ch, ok := eh.EventHandler.(ContextEventHandler); ok {
+		ch.HandleContext(ctx, s, i)
+	} else {
+		eh.EventHandler.Handle(s, i)
+	}
+}
+
+// runHandler calls a handler in a span, recovering panics, reporting it when
+// it outlives its timeout and measuring how long it took.
+func (s *Session) runHandler(h EventHandler, eventType string, i interface{}) {
+	ctx, span := s.tracer().Start(context.Background(), "milky.handler "+eventType)
+	span.SetAttribute("milky.event_type", eventType)
+
+	timeout := s.HandlerTimeout
+	if th, ok := h.(timeoutEventHandler); ok {
+		timeout = th.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		timer := time.AfterFunc(timeout, func() {
+			s.slowHandler(eventType, i, timeout)
+		})
+		defer timer.Stop()
+	}
+
+	start := time.Now()
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+			s.handlerPanic(&HandlerPanic{EventType: eventType, Event: i, Value: r, Stack: debug.Stack()})
+		}
+		s.metrics().ObserveHandler(eventType, time.Since(start))
+		span.End(err)
+	}()
+
+	if ch, ok := h.(ContextEventHandler); ok {
+		ch.HandleContext(ctx, s, i)
+	} else {
+		h.Handle(s, i)
+	}
+}
+
+func (s *Session) handlerPanic(p *HandlerPanic) {
+	if s.OnHandlerPanic != nil {
+		s.OnHandlerPanic(s, p)
+		return
+	}
+	s.log().With("event_type", p.EventType).Errorf("recovered from panic in event handler: %v\n%s", p.Value, p.Stack)
+}
+
+func (s *Session) slowHandler(eventType string, i interface{}, timeout time.Duration) {
+	if s.OnSlowHandler != nil {
+		s.OnSlowHandler(s, eventType, i, timeout)
+		return
+	}
+	s.log().With("event_type", eventType).Warnf("event handler still running after %s", timeout)
+}
+
+// goHandler runs a handler in its own goroutine, counting it as pending
+// until it returns.
+func (s *Session) goHandler(h EventHandler, eventType string, i interface{}) {
+	s.metrics().SetQueueDepth(int(atomic.AddInt64(&s.pendingHandlers, 1)))
+	go func() {
+		defer func() {
+			s.metrics().SetQueueDepth(int(atomic.AddInt64(&s.pendingHandlers, -1)))
+		}()
+		s.runHandler(h, eventType, i)
+	}()
+}
+
 // Handles an event type by calling internal methods, firing handlers and firing the
 // interface{} event.
 func (s *Session) handleEvent(t string, i interface{}) {
+	if pool := s.WorkerPool; pool != nil {
+		s.submitEvent(pool, t, i)
+		return
+	}
+
 	s.handlersMu.RLock()
 	defer s.handlersMu.RUnlock()
 
@@ -175,8 +306,37 @@ func (s *Session) handleEvent(t string, i interface{}) {
 	// s.onInterface(i)
 
 	// Then they are dispatched to anyone handling interface{} events.
-	s.handle(interfaceEventType, i)
+	s.handle(interfaceEventType, t, i)
 
 	// Finally they are dispatched to any typed handlers.
-	s.handle(t, i)
+	s.handle(t, t, i)
+}
+
+// submitEvent queues the handlers for an event on a worker pool, where they
+// run one after the other.
+func (s *Session) submitEvent(pool *WorkerPool, t string, i interface{}) {
+	s.handlersMu.Lock()
+	handlers := append(s.handlers[interfaceEventType][:0:0], s.handlers[interfaceEventType]...)
+	handlers = append(handlers, s.onceHandlers[interfaceEventType]...)
+	handlers = append(handlers, s.handlers[t]...)
+	handlers = append(handlers, s.onceHandlers[t]...)
+	delete(s.onceHandlers, interfaceEventType)
+	delete(s.onceHandlers, t)
+	s.handlersMu.Unlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+
+	key := ConversationKey(i)
+	if !pool.Submit(key, func() {
+		for _, eh := range handlers {
+			s.runHandler(eh.eventHandler, t, i)
+		}
+		// The pool counts the event as pending until this returns.
+		s.metrics().SetQueueDepth(pool.Len() - 1)
+	}) {
+		s.log().With("event_type", t).Warnf("event queue for %q is full or closed, the event was dropped", key)
+	}
+	s.metrics().SetQueueDepth(pool.Len())
 }
@@ -0,0 +1,76 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNudgeFrame = `{"event_type":"group_nudge","data":{"group_id":1,"sender_id":2,"receiver_id":3}}`
+
+func TestHandlerPanicRecovered(t *testing.T) {
+	for _, sync := range []bool{true, false} {
+		s, _ := New("", "", "", &TestLogger{})
+		s.SyncEvents = sync
+		panics := make(chan *HandlerPanic, 1)
+		s.OnHandlerPanic = func(s *Session, p *HandlerPanic) { panics <- p }
+
+		s.AddHandler(func(s *Session, n *GroupNudge) { panic("boom") })
+		if _, err := s.dispatchFrame([]byte(testNudgeFrame)); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case p := <-panics:
+			if p.EventType != groupNudgeEventType || p.Value != "boom" || !strings.Contains(string(p.Stack), "event_test.go") {
+				t.Errorf("sync=%v: unexpected panic report %+v", sync, p)
+			}
+			if n, ok := p.Event.(*GroupNudge); !ok || n.GroupID != 1 {
+				t.Errorf("sync=%v: unexpected event %#v", sync, p.Event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("sync=%v: panic not reported", sync)
+		}
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	s, _ := New("", "", "", &TestLogger{})
+	s.SyncEvents = true
+	s.HandlerTimeout = time.Hour
+
+	slow := make(chan time.Duration, 2)
+	s.OnSlowHandler = func(s *Session, eventType string, event interface{}, timeout time.Duration) {
+		slow <- timeout
+	}
+
+	var ctxErr error
+	s.AddHandler(HandlerWithTimeout(ContextHandler(func(ctx context.Context, s *Session, n *GroupNudge) {
+		<-ctx.Done()
+		ctxErr = ctx.Err()
+		// Stay past the deadline so the slow report is sent while running.
+		time.Sleep(20 * time.Millisecond)
+	}), 10*time.Millisecond))
+	s.AddHandler(func(s *Session, n *GroupNudge) {})
+
+	if _, err := s.dispatchFrame([]byte(testNudgeFrame)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-slow:
+		if d != 10*time.Millisecond {
+			t.Errorf("slow handler reported with timeout %s", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slow handler not reported")
+	}
+	select {
+	case d := <-slow:
+		t.Errorf("unexpected second report with timeout %s", d)
+	default:
+	}
+	if ctxErr != context.DeadlineExceeded {
+		t.Errorf("handler context error %v", ctxErr)
+	}
+}
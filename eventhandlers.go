@@ -25,8 +25,17 @@ const (
 	groupInvitationEventType           = "group_invitation"
 )
 
+// Event types emitted by the SDK itself rather than received from the gateway.
+const (
+	gatewaySwitchEventType = "gateway_switch"
+	readyEventType         = "ready"
+	disconnectEventType    = "disconnect"
+)
+
 func handlerForInterface(handler interface{}) EventHandler {
 	switch v := handler.(type) {
+	case EventHandler:
+		return v
 	case func(*Session, interface{}):
 		return interfaceEventHandler(v)
 	case func(*Session, *ReceiveMessage):
@@ -67,6 +76,12 @@ func handlerForInterface(handler interface{}) EventHandler {
 		return groupNameChangeEventHandler(v)
 	case func(*Session, *GroupFileUpload):
 		return groupFileUploadEventHandler(v)
+	case func(*Session, *GatewaySwitch):
+		return gatewaySwitchEventHandler(v)
+	case func(*Session, *Ready):
+		return readyEventHandler(v)
+	case func(*Session, *Disconnect):
+		return disconnectEventHandler(v)
 	default:
 		return nil
 	}
@@ -385,7 +400,58 @@ func (eh friendFileUploadEventHandler) Handle(s *Session, i interface{}) {
 	}
 }
 
+type gatewaySwitchEventHandler func(*Session, *GatewaySwitch)
+
+func (eh gatewaySwitchEventHandler) Type() string {
+	return gatewaySwitchEventType
+}
+
+func (eh gatewaySwitchEventHandler) New() interface{} {
+	return &GatewaySwitch{}
+}
+
+func (eh gatewaySwitchEventHandler) Handle(s *Session, i interface{}) {
+	if t, ok := i.(*GatewaySwitch); ok {
+		eh(s, t)
+	}
+}
+
+type readyEventHandler func(*Session, *Ready)
+
+func (eh readyEventHandler) Type() string {
+	return readyEventType
+}
+
+func (eh readyEventHandler) New() interface{} {
+	return &Ready{}
+}
+
+func (eh readyEventHandler) Handle(s *Session, i interface{}) {
+	if t, ok := i.(*Ready); ok {
+		eh(s, t)
+	}
+}
+
+type disconnectEventHandler func(*Session, *Disconnect)
+
+func (eh disconnectEventHandler) Type() string {
+	return disconnectEventType
+}
+
+func (eh disconnectEventHandler) New() interface{} {
+	return &Disconnect{}
+}
+
+func (eh disconnectEventHandler) Handle(s *Session, i interface{}) {
+	if t, ok := i.(*Disconnect); ok {
+		eh(s, t)
+	}
+}
+
 func init() {
+	registerInternalEvent(gatewaySwitchEventHandler(nil))
+	registerInternalEvent(readyEventHandler(nil))
+	registerInternalEvent(disconnectEventHandler(nil))
 	registerInterfaceProvider(messageReceiveEventHandler(nil))
 	registerInterfaceProvider(friendRequestEventHandler(nil))
 	registerInterfaceProvider(botOfflineEventHandler(nil))
@@ -6,6 +6,8 @@ import (
 
 // Event provides a basic initial struct for all websocket events.
 type Event struct {
+	Time    int64           `json:"time"`
+	SelfID  int64           `json:"self_id"`
 	Type    string          `json:"event_type"`
 	RawData json.RawMessage `json:"data"`
 	// Struct contains one of the other types in this file.
@@ -13,16 +15,20 @@ type Event struct {
 }
 
 type ReceiveMessage struct {
-	PeerId       int64  `json:"peer_id"`
-	MessageSeq   int64  `json:"message_seq"`
-	SenderId     int64  `json:"sender_id"`
-	Time         int64  `json:"time"`
-	MessageScene string `json:"message_scene"` // "friend", "group", etc.
+	PeerId       int64        `json:"peer_id"`
+	MessageSeq   int64        `json:"message_seq"`
+	SenderId     int64        `json:"sender_id"`
+	Time         int64        `json:"time"`
+	MessageScene MessageScene `json:"message_scene"`
 
 	Segments    []IMessageElement `json:"segments"`
 	Group       *GroupInfo        `json:"group"`
 	Friend      *Friend           `json:"friend"`
 	GroupMember *GroupMemberInfo  `json:"group_member"`
+
+	// FromSelf is true for messages sent by the bot itself, such as echoes
+	// of its own replies.
+	FromSelf bool `json:"-"`
 }
 
 type MessageRet struct {
@@ -192,7 +198,7 @@ type UserProfile struct {
 	Nickname string `json:"nickname"`
 	Qid      string `json:"qid"`
 	Age      int32  `json:"age"`
-	Sex      string `json:"sex"`
+	Sex      Sex    `json:"sex"`
 	Remark   string `json:"remark"`
 	Bio      string `json:"bio"`
 	Level    int32  `json:"level"`
@@ -210,7 +216,7 @@ type Friend struct {
 	UserID   int64           `json:"user_id"`
 	QID      string          `json:"qid,omitempty"`
 	Nickname string          `json:"nickname"`
-	Sex      string          `json:"sex"`
+	Sex      Sex             `json:"sex"`
 	Remark   string          `json:"remark"`
 	Category *FriendCategory `json:"category"`
 }
@@ -228,9 +234,9 @@ type GroupMemberInfo struct {
 	Nickname     string `json:"nickname"`
 	Card         string `json:"card"`
 	Title        string `json:"title"`
-	Sex          string `json:"sex"`
+	Sex          Sex    `json:"sex"`
 	Level        int32  `json:"level"`
-	Role         string `json:"role"` // "owner", "admin", "member"
+	Role         Role   `json:"role"`
 	JoinTime     int64  `json:"join_time"`
 	LastSentTime int64  `json:"last_sent_time"`
 }
@@ -326,16 +332,16 @@ type FriendRequest struct {
 }
 
 type GroupRequest struct {
-	RequestID   string `json:"request_id"`
-	Time        int64  `json:"time"`
-	IsFiltered  bool   `json:"is_filtered"`  // 是否被过滤
-	InitiatorID int64  `json:"initiator_id"` // 发起者ID
-	State       string `json:"state"`
-	GroupID     int64  `json:"group_id"`
-	OperatorID  int64  `json:"operator_id,omitempty"`
-	RequestType string `json:"request_type"`
-	Comment     string `json:"comment,omitempty"`
-	InviteeID   int64  `json:"invitee_id,omitempty"`
+	RequestID   string       `json:"request_id"`
+	Time        int64        `json:"time"`
+	IsFiltered  bool         `json:"is_filtered"`  // 是否被过滤
+	InitiatorID int64        `json:"initiator_id"` // 发起者ID
+	State       RequestState `json:"state"`
+	GroupID     int64        `json:"group_id"`
+	OperatorID  int64        `json:"operator_id,omitempty"`
+	RequestType string       `json:"request_type"`
+	Comment     string       `json:"comment,omitempty"`
+	InviteeID   int64        `json:"invitee_id,omitempty"`
 }
 
 type GroupInvitation struct {
@@ -345,12 +351,12 @@ type GroupInvitation struct {
 }
 
 type MessageRecall struct {
-	MessageScene  string `json:"message_scene"` // "friend", "group", etc.
-	PeerID        int64  `json:"peer_id"`
-	MessageSeq    int64  `json:"message_seq"`
-	SenderID      int64  `json:"sender_id"`
-	OperatorID    int64  `json:"operator_id"`
-	DisplaySuffix string `json:"display_suffix"`
+	MessageScene  MessageScene `json:"message_scene"`
+	PeerID        int64        `json:"peer_id"`
+	MessageSeq    int64        `json:"message_seq"`
+	SenderID      int64        `json:"sender_id"`
+	OperatorID    int64        `json:"operator_id"`
+	DisplaySuffix string       `json:"display_suffix"`
 }
 
 type FriendNudge struct {
@@ -469,64 +475,98 @@ const (
 	InvitedJoinRequestType GroupNotificationType = "invited_join_request"
 )
 
+// GroupNotification is implemented by all notification types returned from
+// GetGroupNotifications. Use a type switch to get at the concrete type.
+type GroupNotification interface {
+	NotificationType() GroupNotificationType
+	Base() *GroupNotificationBase
+
+	// groupNotification seals the interface to the types in this package.
+	groupNotification()
+}
+
+// ActionableGroupNotification is implemented by the notification kinds that
+// can be accepted or rejected.
+type ActionableGroupNotification interface {
+	GroupNotification
+	Accept(s *Session, options ...RequestOption) error
+	Reject(s *Session, reason string, options ...RequestOption) error
+}
+
 type GroupNotificationBase struct {
 	Type            GroupNotificationType `json:"type"`
 	GroupID         int64                 `json:"group_id"`
 	NotificationSeq int64                 `json:"notification_seq"`
 }
 
-func UnmarshalGroupNotification(data json.RawMessage) (interface{}, error) {
+// NotificationType returns the kind of the notification.
+func (b *GroupNotificationBase) NotificationType() GroupNotificationType {
+	return b.Type
+}
+
+// Base returns the fields shared by all notifications.
+func (b *GroupNotificationBase) Base() *GroupNotificationBase {
+	return b
+}
+
+// sealedNotification is embedded by the notification types in this package.
+// It is unexported, so types outside the package cannot implement
+// GroupNotification by embedding GroupNotificationBase.
+type sealedNotification struct{}
+
+func (sealedNotification) groupNotification() {}
+
+// UnmarshalGroupNotification decodes a notification into its concrete type.
+// Kinds this package does not know are returned as *UnknownGroupNotification.
+func UnmarshalGroupNotification(data json.RawMessage) (GroupNotification, error) {
 	var base GroupNotificationBase
-	if err := json.Unmarshal(data, &base); err == nil {
+	if err := json.Unmarshal(data, &base); err != nil {
 		return nil, err
 	}
+	var notification GroupNotification
 	switch base.Type {
 	case JoinRequestType:
-		var joinRequest JoinRequestNotification
-		if err := json.Unmarshal(data, &joinRequest); err != nil {
-			return nil, err
-		}
-		return &joinRequest, nil
+		notification = &JoinRequestNotification{}
 	case AdminChangeType:
-		var adminChange AdminChangeNotification
-		if err := json.Unmarshal(data, &adminChange); err != nil {
-			return nil, err
-		}
-		return &adminChange, nil
+		notification = &AdminChangeNotification{}
 	case KickType:
-		var kick KickNotification
-		if err := json.Unmarshal(data, &kick); err != nil {
-			return nil, err
-		}
-		return &kick, nil
+		notification = &KickNotification{}
 	case QuitType:
-		var quit QuitNotification
-		if err := json.Unmarshal(data, &quit); err != nil {
-			return nil, err
-		}
-		return &quit, nil
+		notification = &QuitNotification{}
 	case InvitedJoinRequestType:
-		var invitedJoinRequest InvitedJoinRequestNotification
-		if err := json.Unmarshal(data, &invitedJoinRequest); err != nil {
-			return nil, err
-		}
-		return &invitedJoinRequest, nil
+		notification = &InvitedJoinRequestNotification{}
 	default:
-		return nil, nil
+		return &UnknownGroupNotification{GroupNotificationBase: base, RawData: data}, nil
+	}
+	if err := json.Unmarshal(data, notification); err != nil {
+		return nil, err
 	}
+	return notification, nil
 }
 
 type JoinRequestNotification struct {
 	GroupNotificationBase
-	IsFiltered  bool   `json:"is_filtered"`
-	InitiatorID int64  `json:"initiator_id"`
-	State       string `json:"state"`
-	OperatorID  int64  `json:"operator_id"`
-	Comment     string `json:"comment"`
+	sealedNotification
+	IsFiltered  bool         `json:"is_filtered"`
+	InitiatorID int64        `json:"initiator_id"`
+	State       RequestState `json:"state"`
+	OperatorID  int64        `json:"operator_id"`
+	Comment     string       `json:"comment"`
+}
+
+// Accept accepts the join request.
+func (n *JoinRequestNotification) Accept(s *Session, options ...RequestOption) error {
+	return s.AcceptGroupRequest(n.NotificationSeq, string(n.Type), n.GroupID, n.IsFiltered, options...)
+}
+
+// Reject rejects the join request with an optional reason.
+func (n *JoinRequestNotification) Reject(s *Session, reason string, options ...RequestOption) error {
+	return s.RejectGroupRequest(n.NotificationSeq, string(n.Type), n.GroupID, n.IsFiltered, reason, options...)
 }
 
 type AdminChangeNotification struct {
 	GroupNotificationBase
+	sealedNotification
 	TargetUserID int64 `json:"target_user_id"`
 	IsSet        bool  `json:"is_set"`
 	OperatorID   int64 `json:"operator_id"`
@@ -534,19 +574,40 @@ type AdminChangeNotification struct {
 
 type KickNotification struct {
 	GroupNotificationBase
+	sealedNotification
 	TargetUserID int64 `json:"target_user_id"`
 	OperatorID   int64 `json:"operator_id"`
 }
 
 type QuitNotification struct {
 	GroupNotificationBase
+	sealedNotification
 	TargetUserID int64 `json:"target_user_id"`
 }
 
 type InvitedJoinRequestNotification struct {
 	GroupNotificationBase
-	InitiatorID  int64  `json:"initiator_id"`
-	TargetUserID int64  `json:"target_user_id"`
-	State        string `json:"state"`
-	OperatorID   int64  `json:"operator_id"`
+	sealedNotification
+	InitiatorID  int64        `json:"initiator_id"`
+	TargetUserID int64        `json:"target_user_id"`
+	State        RequestState `json:"state"`
+	OperatorID   int64        `json:"operator_id"`
+}
+
+// Accept accepts the invited join request.
+func (n *InvitedJoinRequestNotification) Accept(s *Session, options ...RequestOption) error {
+	return s.AcceptGroupRequest(n.NotificationSeq, string(n.Type), n.GroupID, false, options...)
+}
+
+// Reject rejects the invited join request with an optional reason.
+func (n *InvitedJoinRequestNotification) Reject(s *Session, reason string, options ...RequestOption) error {
+	return s.RejectGroupRequest(n.NotificationSeq, string(n.Type), n.GroupID, false, reason, options...)
+}
+
+// UnknownGroupNotification holds a notification of a kind this package does
+// not know yet.
+type UnknownGroupNotification struct {
+	GroupNotificationBase
+	sealedNotification
+	RawData json.RawMessage `json:"-"`
 }
@@ -0,0 +1,605 @@
+package Milky_go_sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat selects the output written by HistoryExporter.
+type ExportFormat string
+
+const (
+	ExportJSONL    ExportFormat = "jsonl"
+	ExportHTML     ExportFormat = "html"
+	ExportMarkdown ExportFormat = "markdown"
+)
+
+// ExportedSegment is a message segment as written by the JSONL exporter.
+type ExportedSegment struct {
+	Type  MessageElementType `json:"type"`
+	Data  interface{}        `json:"data"`
+	Media *StoredResource    `json:"media,omitempty"`
+
+	// Set when the media resource could not be downloaded.
+	MediaError string `json:"media_error,omitempty"`
+}
+
+// ExportedMessage is one line of a JSONL export.
+type ExportedMessage struct {
+	MessageScene MessageScene      `json:"message_scene"`
+	PeerID       int64             `json:"peer_id"`
+	MessageSeq   int64             `json:"message_seq"`
+	Time         int64             `json:"time"`
+	SenderID     int64             `json:"sender_id"`
+	SenderName   string            `json:"sender_name"`
+	Text         string            `json:"text"`
+	Segments     []ExportedSegment `json:"segments"`
+}
+
+// ExportResult summarises an export run. LastMessageSeq is the value to pass
+// as AfterSeq to continue the export later.
+type ExportResult struct {
+	Count           int
+	FirstMessageSeq int64
+	LastMessageSeq  int64
+}
+
+// HistoryExporter writes a conversation's history for a time range.
+type HistoryExporter struct {
+	Session      *Session
+	MessageScene MessageScene
+	PeerID       int64
+
+	// Time range to export, zero values leave the range open.
+	Since time.Time
+	Until time.Time
+
+	// Resume point, only messages with a greater sequence are exported.
+	AfterSeq int64
+
+	// When set, media resources are downloaded and referenced from the
+	// export; HTML transcripts embed images inline.
+	Media *MediaDownloader
+
+	// Title of HTML and Markdown transcripts.
+	Title string
+
+	PageSize int32
+
+	names map[int64]string
+}
+
+// NewHistoryExporter returns a HistoryExporter for one conversation.
+func NewHistoryExporter(s *Session, messageScene MessageScene, peerID int64) *HistoryExporter {
+	return &HistoryExporter{
+		Session:      s,
+		MessageScene: messageScene,
+		PeerID:       peerID,
+		Title:        fmt.Sprintf("%s %d", messageScene, peerID),
+		PageSize:     50,
+	}
+}
+
+// Export fetches the configured range and writes it to w, oldest message
+// first. History is walked once, newest first, into a temporary file, then
+// written out one message at a time. A failed media download is recorded on
+// its segment rather than aborting the export. When the export fails part
+// way, the messages written so far are flushed and the partial result is
+// returned with the error, so the export can be resumed from it.
+func (e *HistoryExporter) Export(ctx context.Context, w io.Writer, format ExportFormat) (*ExportResult, error) {
+	result := &ExportResult{LastMessageSeq: e.AfterSeq}
+	out, err := newTranscriptWriter(w, format, e.Title)
+	if err != nil {
+		return result, err
+	}
+	if err = validMessageScene(e.MessageScene); err != nil {
+		return result, err
+	}
+	spool, err := e.spool(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer spool.close()
+
+	if err = e.writeSpool(ctx, out, spool, result); err != nil {
+		_ = out.flush()
+		return result, err
+	}
+	return result, out.end()
+}
+
+// writeSpool writes the spooled pages oldest first, updating result after
+// every message.
+func (e *HistoryExporter) writeSpool(ctx context.Context, out *transcriptWriter, spool *historySpool, result *ExportResult) error {
+	if err := out.begin(); err != nil {
+		return err
+	}
+	for i := len(spool.pages) - 1; i >= 0; i-- {
+		messages, err := spool.page(i)
+		if err != nil {
+			return err
+		}
+		sort.Slice(messages, func(a, b int) bool { return messages[a].MessageSeq < messages[b].MessageSeq })
+		for j := range messages {
+			m := &messages[j]
+			// Pages of a changing history may overlap.
+			if m.MessageSeq <= result.LastMessageSeq {
+				continue
+			}
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+			if err = out.write(e.export(ctx, m)); err != nil {
+				return err
+			}
+			if result.Count == 0 {
+				result.FirstMessageSeq = m.MessageSeq
+			}
+			result.Count++
+			result.LastMessageSeq = m.MessageSeq
+		}
+	}
+	return nil
+}
+
+// spool walks history from the latest message back to the start of the
+// range, keeping the messages in range in a temporary file.
+func (e *HistoryExporter) spool(ctx context.Context) (*historySpool, error) {
+	spool, err := newHistorySpool()
+	if err != nil {
+		return nil, err
+	}
+	var cursor int64
+	for {
+		page, next, err := e.historyPage(ctx, cursor)
+		if err != nil {
+			spool.close()
+			return nil, err
+		}
+
+		var keep []json.RawMessage
+		done := next == 0 || len(page) == 0
+		for _, raw := range page {
+			var m struct {
+				MessageSeq int64 `json:"message_seq"`
+				Time       int64 `json:"time"`
+			}
+			if err = json.Unmarshal(raw, &m); err != nil {
+				spool.close()
+				return nil, fmt.Errorf("%w: %s", ErrJSONUnmarshal, err)
+			}
+			// History is paged newest first, a page reaching past the start
+			// of the range is the last one.
+			if m.MessageSeq <= e.AfterSeq || (!e.Since.IsZero() && m.Time < e.Since.Unix()) {
+				done = true
+				continue
+			}
+			if e.Until.IsZero() || m.Time <= e.Until.Unix() {
+				keep = append(keep, raw)
+			}
+		}
+		if err = spool.add(keep); err != nil {
+			spool.close()
+			return nil, err
+		}
+		if done {
+			return spool, nil
+		}
+		cursor = next
+	}
+}
+
+// historyPage fetches one page of history as raw messages.
+func (e *HistoryExporter) historyPage(ctx context.Context, startMessageSeq int64) ([]json.RawMessage, int64, error) {
+	data := map[string]interface{}{
+		"message_scene": e.MessageScene,
+		"peer_id":       e.PeerID,
+		"limit":         e.pageSize(),
+	}
+	if startMessageSeq > 0 {
+		data["start_message_seq"] = startMessageSeq
+	}
+	request, err := e.Session.Request("POST", EndpointGetHistoryMessages, data, WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	var apiResponse APIResponse
+	var historyMessages struct {
+		Messages       []json.RawMessage `json:"messages"`
+		NextMessageSeq int64             `json:"next_message_seq,omitempty"`
+	}
+	if err = handleAPIResponse(request, &apiResponse, &historyMessages); err != nil {
+		return nil, 0, err
+	}
+	return historyMessages.Messages, historyMessages.NextMessageSeq, nil
+}
+
+// historySpool keeps pages of raw history messages in a temporary file, so
+// an export holds one page in memory at a time.
+type historySpool struct {
+	f     *os.File
+	size  int64
+	pages []spooledPage // newest first
+}
+
+type spooledPage struct {
+	offset, size int64
+}
+
+func newHistorySpool() (*historySpool, error) {
+	f, err := os.CreateTemp("", "milky-export-*.json")
+	if err != nil {
+		return nil, err
+	}
+	return &historySpool{f: f}, nil
+}
+
+func (sp *historySpool) add(messages []json.RawMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	if _, err = sp.f.Write(data); err != nil {
+		return err
+	}
+	sp.pages = append(sp.pages, spooledPage{offset: sp.size, size: int64(len(data))})
+	sp.size += int64(len(data))
+	return nil
+}
+
+func (sp *historySpool) page(i int) ([]ReceiveMessage, error) {
+	data := make([]byte, sp.pages[i].size)
+	if _, err := sp.f.ReadAt(data, sp.pages[i].offset); err != nil {
+		return nil, err
+	}
+	var messages []ReceiveMessage
+	if err := unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (sp *historySpool) close() {
+	_ = sp.f.Close()
+	_ = os.Remove(sp.f.Name())
+}
+
+func (e *HistoryExporter) pageSize() int32 {
+	if e.PageSize <= 0 {
+		return defaultIteratorPageSize
+	}
+	return e.PageSize
+}
+
+func (e *HistoryExporter) export(ctx context.Context, m *ReceiveMessage) *ExportedMessage {
+	exported := &ExportedMessage{
+		MessageScene: m.MessageScene,
+		PeerID:       m.PeerId,
+		MessageSeq:   m.MessageSeq,
+		Time:         m.Time,
+		SenderID:     m.SenderId,
+		SenderName:   e.senderName(ctx, m),
+		Text:         PlainText(m.Segments),
+		Segments:     make([]ExportedSegment, 0, len(m.Segments)),
+	}
+	for _, segment := range m.Segments {
+		s := ExportedSegment{Type: segment.Type(), Data: reflect.Indirect(reflect.ValueOf(segment)).Interface()}
+		if _, _, ok := ResourceOf(segment); ok && e.Media != nil {
+			stored, err := e.Media.Save(ctx, segment)
+			if err != nil {
+				e.Session.log().Warnf("error saving media of message %d, %s", m.MessageSeq, err)
+				s.MediaError = err.Error()
+			}
+			s.Media = stored
+		}
+		exported.Segments = append(exported.Segments, s)
+	}
+	return exported
+}
+
+// senderName resolves a display name, preferring the group card, and caches
+// lookups made through GetGroupMemberInfo.
+func (e *HistoryExporter) senderName(ctx context.Context, m *ReceiveMessage) string {
+	if m.GroupMember != nil {
+		return memberDisplayName(m.GroupMember)
+	}
+	if m.Friend != nil {
+		return m.Friend.Nickname
+	}
+	if name, ok := e.names[m.SenderId]; ok {
+		return name
+	}
+	if e.names == nil {
+		e.names = map[int64]string{}
+	}
+
+	name := strconv.FormatInt(m.SenderId, 10)
+	if m.MessageScene == SceneGroup {
+		member, err := e.Session.GetGroupMemberInfo(m.PeerId, m.SenderId, false, WithContext(ctx))
+		if err != nil {
+			e.Session.log().Debugf("error resolving group member %d in %d, %s", m.SenderId, m.PeerId, err)
+		} else {
+			name = memberDisplayName(member)
+		}
+	}
+	e.names[m.SenderId] = name
+	return name
+}
+
+func memberDisplayName(member *GroupMemberInfo) string {
+	if member.Card != "" {
+		return member.Card
+	}
+	return member.Nickname
+}
+
+// LastExportedSeq scans a JSONL export and returns the highest message_seq
+// found, for resuming with HistoryExporter.AfterSeq.
+func LastExportedSeq(r io.Reader) (int64, error) {
+	var last int64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxFileSize)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var m struct {
+			MessageSeq int64 `json:"message_seq"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return last, err
+		}
+		if m.MessageSeq > last {
+			last = m.MessageSeq
+		}
+	}
+	return last, scanner.Err()
+}
+
+// PlainText renders message segments as a single line of text.
+func PlainText(segments []IMessageElement) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case *TextElement:
+			b.WriteString(s.Text)
+		case *AtElement:
+			fmt.Fprintf(&b, "@%d", s.UserID)
+		case *AtAllElement:
+			b.WriteString("@all")
+		case *FaceElement:
+			fmt.Fprintf(&b, "[face:%s]", s.FaceID)
+		case *ReplyElement:
+			fmt.Fprintf(&b, "[reply:%d]", s.MessageSeq)
+		case *ImageElement:
+			if s.Summary != "" {
+				fmt.Fprintf(&b, "[image:%s]", s.Summary)
+			} else {
+				b.WriteString("[image]")
+			}
+		case *LightAppElement:
+			fmt.Fprintf(&b, "[%s:%s]", s.Type(), s.AppName)
+		default:
+			fmt.Fprintf(&b, "[%s]", segment.Type())
+		}
+	}
+	return b.String()
+}
+
+// transcriptWriter writes exported messages to w one at a time. Each
+// message is rendered in full before any of it is written, so a failed
+// export leaves only complete records behind.
+type transcriptWriter struct {
+	w      *bufio.Writer
+	format ExportFormat
+	title  string
+	record bytes.Buffer
+	enc    *json.Encoder
+}
+
+func newTranscriptWriter(w io.Writer, format ExportFormat, title string) (*transcriptWriter, error) {
+	switch format {
+	case ExportJSONL, ExportHTML, ExportMarkdown:
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	t := &transcriptWriter{w: bufio.NewWriter(w), format: format, title: title}
+	t.enc = json.NewEncoder(&t.record)
+	t.enc.SetEscapeHTML(false)
+	return t, nil
+}
+
+func (t *transcriptWriter) begin() error {
+	switch t.format {
+	case ExportHTML:
+		return htmlTranscript.ExecuteTemplate(t.w, "head", t.title)
+	case ExportMarkdown:
+		_, err := fmt.Fprintf(t.w, "# %s\n\n", t.title)
+		return err
+	}
+	return nil
+}
+
+func (t *transcriptWriter) write(m *ExportedMessage) error {
+	t.record.Reset()
+	var err error
+	switch t.format {
+	case ExportHTML:
+		err = htmlTranscript.ExecuteTemplate(&t.record, "message", htmlView(m))
+	case ExportMarkdown:
+		err = writeMarkdownMessage(&t.record, m)
+	default:
+		err = t.enc.Encode(m)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = t.w.Write(t.record.Bytes())
+	return err
+}
+
+// flush writes out the records buffered so far.
+func (t *transcriptWriter) flush() error {
+	return t.w.Flush()
+}
+
+func (t *transcriptWriter) end() error {
+	if t.format == ExportHTML {
+		if err := htmlTranscript.ExecuteTemplate(t.w, "foot", nil); err != nil {
+			return err
+		}
+	}
+	return t.w.Flush()
+}
+
+func writeMarkdownMessage(w io.Writer, m *ExportedMessage) error {
+	fmt.Fprintf(w, "<a id=\"msg-%d\"></a>\n**%s** (%d) · %s · #%d\n\n",
+		m.MessageSeq, escapeMarkdown(m.SenderName), m.SenderID, time.Unix(m.Time, 0).Format(time.DateTime), m.MessageSeq)
+	var line strings.Builder
+	for _, s := range m.Segments {
+		switch data := s.Data.(type) {
+		case TextElement:
+			line.WriteString(escapeMarkdown(data.Text))
+		case ReplyElement:
+			fmt.Fprintf(&line, "[↩ #%d](#msg-%d) ", data.MessageSeq, data.MessageSeq)
+		case ImageElement:
+			fmt.Fprintf(&line, "![%s](%s)", escapeMarkdown(data.Summary), mediaLink(s, data.TempURL))
+		case RecordElement:
+			fmt.Fprintf(&line, "[record](%s)", mediaLink(s, data.TempURL))
+		case VideoElement:
+			fmt.Fprintf(&line, "[video](%s)", mediaLink(s, data.TempURL))
+		default:
+			line.WriteString(escapeMarkdown(PlainText([]IMessageElement{segmentElement(s)})))
+		}
+	}
+	_, err := fmt.Fprintf(w, "> %s\n\n", strings.ReplaceAll(line.String(), "\n", "\n> "))
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;", "#", `\#`)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// mediaLink prefers the downloaded copy of a resource over its temporary URL.
+func mediaLink(s ExportedSegment, tempURL string) string {
+	if s.Media != nil {
+		return s.Media.Path
+	}
+	return tempURL
+}
+
+// segmentElement turns an exported segment back into its message element.
+func segmentElement(s ExportedSegment) IMessageElement {
+	v := reflect.New(reflect.TypeOf(s.Data))
+	v.Elem().Set(reflect.ValueOf(s.Data))
+	element, _ := v.Interface().(IMessageElement)
+	return element
+}
+
+// htmlMessage is the view of an ExportedMessage used by the HTML template.
+type htmlMessage struct {
+	ExportedMessage
+	Time  string
+	Parts []htmlPart
+}
+
+type htmlPart struct {
+	Text     string
+	ReplyTo  int64
+	ImageSrc template.URL
+	Link     template.URL
+	Label    string
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Parse(`{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body{font-family:sans-serif;max-width:48em;margin:auto;padding:1em;background:#fafafa}
+.msg{background:#fff;border-radius:6px;padding:.5em .8em;margin:.5em 0;box-shadow:0 1px 2px #0002}
+.meta{color:#666;font-size:.85em}
+.meta a{color:inherit}
+.reply{display:inline-block;border-left:3px solid #aaa;padding-left:.4em;margin-right:.4em;color:#555}
+img{max-width:100%;display:block;margin:.3em 0}
+.content{white-space:pre-wrap}
+:target{outline:2px solid #4a90d9}
+</style>
+</head>
+<body>
+<h1>{{.}}</h1>
+{{end}}{{define "message"}}<div class="msg" id="msg-{{.MessageSeq}}">
+<div class="meta"><b>{{.SenderName}}</b> ({{.SenderID}}) · {{.Time}} · <a href="#msg-{{.MessageSeq}}">#{{.MessageSeq}}</a></div>
+<div class="content">{{range .Parts}}{{if .ReplyTo}}<a class="reply" href="#msg-{{.ReplyTo}}">↩ #{{.ReplyTo}}</a>{{else if .ImageSrc}}<img src="{{.ImageSrc}}" alt="{{.Label}}">{{else if .Link}}<a href="{{.Link}}">{{.Label}}</a>{{else}}{{.Text}}{{end}}{{end}}</div>
+</div>
+{{end}}{{define "foot"}}</body>
+</html>
+{{end}}`))
+
+func htmlView(m *ExportedMessage) htmlMessage {
+	hm := htmlMessage{ExportedMessage: *m, Time: time.Unix(m.Time, 0).Format(time.DateTime)}
+	for _, s := range m.Segments {
+		switch data := s.Data.(type) {
+		case TextElement:
+			hm.Parts = append(hm.Parts, htmlPart{Text: data.Text})
+		case ReplyElement:
+			hm.Parts = append(hm.Parts, htmlPart{ReplyTo: data.MessageSeq})
+		case ImageElement:
+			hm.Parts = append(hm.Parts, htmlPart{ImageSrc: inlineImage(s, data.TempURL), Label: data.Summary})
+		case RecordElement:
+			hm.Parts = append(hm.Parts, htmlPart{Link: safeURL(mediaLink(s, data.TempURL)), Label: "[record]"})
+		case VideoElement:
+			hm.Parts = append(hm.Parts, htmlPart{Link: safeURL(mediaLink(s, data.TempURL)), Label: "[video]"})
+		default:
+			hm.Parts = append(hm.Parts, htmlPart{Text: PlainText([]IMessageElement{segmentElement(s)})})
+		}
+	}
+	return hm
+}
+
+// inlineImage returns a data: URI for a downloaded image so the transcript
+// stays self-contained, falling back to the temporary URL.
+func inlineImage(s ExportedSegment, tempURL string) template.URL {
+	if s.Media == nil {
+		return safeURL(tempURL)
+	}
+	data, err := os.ReadFile(s.Media.Path)
+	if err != nil {
+		return safeURL(tempURL)
+	}
+	return template.URL("data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// safeURL only lets web and local file links through, temporary URLs come
+// from the server and must not be able to inject script.
+func safeURL(u string) template.URL {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	switch parsed.Scheme {
+	case "", "http", "https", "file":
+		return template.URL(u)
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package Milky_go_sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistoryExporter(t *testing.T) {
+	history := []map[string]interface{}{
+		{"peer_id": 1, "message_seq": 3, "sender_id": 20, "time": 1003, "message_scene": "group",
+			"segments": []map[string]interface{}{
+				{"type": "reply", "data": map[string]interface{}{"message_seq": 2}},
+				{"type": "text", "data": map[string]interface{}{"text": "agreed <b>"}},
+			}},
+		{"peer_id": 1, "message_seq": 2, "sender_id": 10, "time": 1002, "message_scene": "group",
+			"segments": []map[string]interface{}{{"type": "text", "data": map[string]interface{}{"text": "second"}}}},
+		{"peer_id": 1, "message_seq": 1, "sender_id": 10, "time": 1001, "message_scene": "group",
+			"segments": []map[string]interface{}{{"type": "text", "data": map[string]interface{}{"text": "first"}}}},
+	}
+
+	historyCalls := 0
+	var onLookup func()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data interface{}
+		switch r.URL.Path {
+		case "/" + EndpointGetHistoryMessages:
+			historyCalls++
+			var req struct {
+				StartMessageSeq int64 `json:"start_message_seq"`
+				Limit           int   `json:"limit"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			var page []map[string]interface{}
+			var next int64
+			for _, m := range history {
+				seq := int64(m["message_seq"].(int))
+				if req.StartMessageSeq != 0 && seq > req.StartMessageSeq {
+					continue
+				}
+				if len(page) == req.Limit {
+					next = seq
+					break
+				}
+				page = append(page, m)
+			}
+			data = map[string]interface{}{"messages": page, "next_message_seq": next}
+		case "/" + EndpointGetGroupMemberInfo:
+			var req struct {
+				UserID int64 `json:"user_id"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if onLookup != nil {
+				onLookup()
+			}
+			data = map[string]interface{}{"member": GroupMemberInfo{UserId: req.UserID, Nickname: "nick", Card: map[int64]string{10: "Alice", 20: "Bob"}[req.UserID]}}
+		}
+		raw, _ := json.Marshal(data)
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: raw})
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	e := NewHistoryExporter(s, "group", 1)
+	e.PageSize = 2
+
+	var jsonl bytes.Buffer
+	result, err := e.Export(context.Background(), &jsonl, ExportJSONL)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if result.Count != 3 || result.FirstMessageSeq != 1 || result.LastMessageSeq != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if historyCalls != 2 {
+		t.Fatalf("expected history to be walked once in 2 pages, got %d requests", historyCalls)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	var first ExportedMessage
+	if err = json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.MessageSeq != 1 || first.SenderName != "Alice" || first.Text != "first" {
+		t.Fatalf("unexpected first line %s", lines[0])
+	}
+	if last, err := LastExportedSeq(&jsonl); err != nil || last != 3 {
+		t.Fatalf("LastExportedSeq = %d, %v", last, err)
+	}
+
+	var html bytes.Buffer
+	e.AfterSeq = 1
+	if result, err = e.Export(context.Background(), &html, ExportHTML); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("expected resumed export to skip exported messages, got %+v", result)
+	}
+	for _, want := range []string{`id="msg-3"`, `href="#msg-2"`, "agreed &lt;b&gt;", "Bob"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML transcript is missing %q", want)
+		}
+	}
+
+	var md bytes.Buffer
+	if _, err = e.Export(context.Background(), &md, ExportMarkdown); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if !strings.Contains(md.String(), "[↩ #2](#msg-2)") {
+		t.Errorf("Markdown transcript is missing reply link:\n%s", md.String())
+	}
+
+	// An export that fails part way keeps the complete records written so
+	// far and reports where to resume.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	onLookup = cancel
+	e = NewHistoryExporter(s, "group", 1)
+	var partial bytes.Buffer
+	result, err = e.Export(ctx, &partial, ExportJSONL)
+	if err == nil {
+		t.Fatal("expected the cancelled export to fail")
+	}
+	if result == nil || result.Count != 1 || result.LastMessageSeq != 1 {
+		t.Fatalf("unexpected partial result %+v", result)
+	}
+	if last, err := LastExportedSeq(&partial); err != nil || last != result.LastMessageSeq {
+		t.Fatalf("LastExportedSeq of the partial export = %d, %v", last, err)
+	}
+}
+
+func TestHistoryExporterRecordsMediaErrors(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		history := []map[string]interface{}{
+			{"peer_id": 1, "message_seq": 2, "sender_id": 10, "time": 1002, "message_scene": "friend",
+				"segments": []map[string]interface{}{{"type": "text", "data": map[string]interface{}{"text": "still here"}}}},
+			{"peer_id": 1, "message_seq": 1, "sender_id": 10, "time": 1001, "message_scene": "friend",
+				"segments": []map[string]interface{}{{"type": "image", "data": map[string]interface{}{"temp_url": server.URL + "/gone"}}}},
+		}
+		raw, _ := json.Marshal(map[string]interface{}{"messages": history})
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: raw})
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	e := NewHistoryExporter(s, SceneFriend, 1)
+	e.Media = NewMediaDownloader(s, t.TempDir(), 1)
+
+	var jsonl bytes.Buffer
+	result, err := e.Export(context.Background(), &jsonl, ExportJSONL)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("expected the export to continue past the failed download, got %+v", result)
+	}
+	var first ExportedMessage
+	if err = json.Unmarshal([]byte(strings.SplitN(jsonl.String(), "\n", 2)[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if segment := first.Segments[0]; segment.Media != nil || !strings.Contains(segment.MediaError, "404") {
+		t.Fatalf("expected a recorded media error, got %+v", segment)
+	}
+}
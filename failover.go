@@ -0,0 +1,288 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// All failover error constants
+var (
+	ErrNoGateways     = errors.New("no gateways configured for failover")
+	ErrAccountChanged = errors.New("gateway is logged in to a different account")
+)
+
+// GatewayPair is one Milky implementation instance, reached through its
+// websocket and REST gateways.
+type GatewayPair struct {
+	WSGateway   string
+	RestGateway string
+}
+
+// GatewaySwitch is emitted on the session when Failover switches gateways.
+type GatewaySwitch struct {
+	From     int // index in Failover.Gateways, -1 if the session was on none of them
+	To       int
+	Gateway  GatewayPair
+	Failback bool   // true when returning to a gateway preferred over the current one
+	Reason   string // why the previous gateway was left
+	Impl     *ImplInfo
+}
+
+// Failover health checks a session's gateway with GetLoginInfo and moves the
+// session to the next gateway after repeated failures. When a more preferred
+// gateway has been healthy for FailbackThreshold checks in a row, the
+// session fails back to it. A gateway is only used if
+// it is logged in to SelfID and answers GetImplInfo.
+type Failover struct {
+	Session *Session
+
+	// Gateways in order of preference, the first is the primary.
+	Gateways []GatewayPair
+
+	// The account every gateway must be logged in to. When 0 it is learned
+	// from the first successful health check.
+	SelfID int64
+
+	// Time between health checks, 10 seconds by default.
+	Interval time.Duration
+
+	// Consecutive failed health checks before failing over, 3 by default.
+	FailureThreshold int
+
+	// Consecutive healthy checks of a preferred gateway before failing back
+	// to it, 3 by default.
+	FailbackThreshold int
+
+	// Time allowed for each probe request, 5 seconds by default.
+	ProbeTimeout time.Duration
+
+	mu       sync.Mutex
+	current  int
+	failures int
+	healthy  map[int]int // consecutive healthy checks of preferred gateways
+}
+
+// NewFailover returns a Failover for s over gateways. The session stays on
+// its current gateway if it is one of them, and is otherwise moved to the
+// first one by the first health check.
+func NewFailover(s *Session, gateways ...GatewayPair) *Failover {
+	f := &Failover{
+		Session:           s,
+		Gateways:          gateways,
+		Interval:          10 * time.Second,
+		FailureThreshold:  3,
+		FailbackThreshold: 3,
+		ProbeTimeout:      5 * time.Second,
+		current:           -1,
+	}
+	s.RLock()
+	for i, gw := range gateways {
+		if gw.RestGateway == s.RestGateway && gw.WSGateway == s.WSGateway {
+			f.current = i
+			break
+		}
+	}
+	s.RUnlock()
+	return f
+}
+
+// Current returns the index of the gateway in use, -1 if none has been
+// selected yet.
+func (f *Failover) Current() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.current
+}
+
+// Run health checks every Interval until ctx is done.
+func (f *Failover) Run(ctx context.Context) error {
+	if len(f.Gateways) == 0 {
+		return ErrNoGateways
+	}
+	interval := f.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f.Check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check runs one health check round: it probes the current gateway, fails
+// over once FailureThreshold checks in a row have failed, and fails back if
+// a preferred gateway is healthy again.
+func (f *Failover) Check(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.Gateways) == 0 {
+		return
+	}
+
+	if f.current < 0 {
+		f.failover(ctx, "no gateway selected")
+		return
+	}
+
+	err := f.probe(ctx, f.Gateways[f.current])
+	if err == nil {
+		f.failures = 0
+		f.checkFailback(ctx)
+		return
+	}
+
+	f.failures++
+	f.Session.log().With("gateway", f.Gateways[f.current].RestGateway).Warnf("gateway health check failed (%d/%d), %s", f.failures, f.threshold(), err)
+	if f.failures >= f.threshold() || errors.Is(err, ErrAccountChanged) {
+		f.failover(ctx, err.Error())
+	}
+}
+
+// checkFailback probes the gateways preferred over the current one, most
+// preferred first, and stops at the first healthy one. It fails back once
+// that gateway was healthy FailbackThreshold checks in a row.
+func (f *Failover) checkFailback(ctx context.Context) {
+	if f.healthy == nil {
+		f.healthy = map[int]int{}
+	}
+	for i := 0; i < f.current; i++ {
+		impl, err := f.verify(ctx, f.Gateways[i])
+		if err != nil {
+			delete(f.healthy, i)
+			continue
+		}
+		f.healthy[i]++
+		if f.healthy[i] >= f.failbackThreshold() {
+			f.switchTo(i, impl, true, "preferred gateway recovered")
+		}
+		return
+	}
+}
+
+// failover switches to the next healthy gateway after the current one,
+// wrapping around to the primary.
+func (f *Failover) failover(ctx context.Context, reason string) {
+	n := len(f.Gateways)
+	for k := 1; k <= n; k++ {
+		i := (f.current + k) % n
+		if f.current < 0 {
+			i = k - 1
+		}
+		if i == f.current {
+			continue
+		}
+		gw := f.Gateways[i]
+		impl, err := f.verify(ctx, gw)
+		if err != nil {
+			f.Session.log().With("gateway", gw.RestGateway).Debugf("failover candidate rejected, %s", err)
+			continue
+		}
+		f.switchTo(i, impl, i < f.current, reason)
+		return
+	}
+	f.Session.log().Errorf("no healthy gateway to fail over to, %s", reason)
+}
+
+// probe checks that a gateway answers GetLoginInfo for the expected account.
+func (f *Failover) probe(ctx context.Context, gw GatewayPair) error {
+	ctx, cancel := context.WithTimeout(ctx, f.probeTimeout())
+	defer cancel()
+	info, err := f.prober(gw).GetLoginInfo(WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if f.SelfID == 0 {
+		f.SelfID = info.UIN
+	} else if info.UIN != f.SelfID {
+		return fmt.Errorf("%w: %d, expected %d", ErrAccountChanged, info.UIN, f.SelfID)
+	}
+	return nil
+}
+
+// verify probes a candidate gateway and fetches its implementation info.
+func (f *Failover) verify(ctx context.Context, gw GatewayPair) (*ImplInfo, error) {
+	if err := f.probe(ctx, gw); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, f.probeTimeout())
+	defer cancel()
+	return f.prober(gw).GetImplInfo(WithContext(ctx))
+}
+
+// prober returns a session for one-off requests to gw, without retries.
+func (f *Failover) prober(gw GatewayPair) *Session {
+	s := f.Session
+	s.RLock()
+	defer s.RUnlock()
+	return &Session{
+		Token:        s.Token,
+		Client:       s.Client,
+		UserAgent:    s.UserAgent,
+		Logger:       s.Logger,
+		LogLevel:     s.LogLevel,
+		apiEndpoints: newAPIEndpoints(gw.RestGateway),
+	}
+}
+
+// switchTo moves the session to gateway i, reconnecting the websocket if it
+// was open, and emits a GatewaySwitch event.
+func (f *Failover) switchTo(i int, impl *ImplInfo, failback bool, reason string) {
+	s := f.Session
+	gw := f.Gateways[i]
+	event := &GatewaySwitch{From: f.current, To: i, Gateway: gw, Failback: failback, Reason: reason, Impl: impl}
+	f.current = i
+	f.failures = 0
+	f.healthy = nil
+
+	s.log().With("gateway", gw.RestGateway).Infof("switching to gateway %d, %s", i, reason)
+	s.setGateways(gw.WSGateway, gw.RestGateway)
+
+	s.RLock()
+	connected := s.wsConn != nil
+	s.RUnlock()
+	if connected {
+		if err := s.Close(); err != nil {
+			s.log().Warnf("error closing websocket to previous gateway, %s", err)
+		}
+		if err := s.Open(); err != nil {
+			s.log().Errorf("error connecting to gateway %s, %s", gw.WSGateway, err)
+			go s.reconnect()
+		}
+	} else if s.wakeReconnect() {
+		// The websocket had already dropped, connect to the new gateway
+		// now rather than after the reconnect backoff.
+		s.log().Debugf("reconnecting to gateway %s now", gw.WSGateway)
+	}
+
+	s.handleEvent(gatewaySwitchEventType, event)
+}
+
+func (f *Failover) threshold() int {
+	if f.FailureThreshold <= 0 {
+		return 1
+	}
+	return f.FailureThreshold
+}
+
+func (f *Failover) failbackThreshold() int {
+	if f.FailbackThreshold <= 0 {
+		return 1
+	}
+	return f.FailbackThreshold
+}
+
+func (f *Failover) probeTimeout() time.Duration {
+	if f.ProbeTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return f.ProbeTimeout
+}
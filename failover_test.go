@@ -0,0 +1,149 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newProbeServer serves get_login_info for uin and get_impl_info, failing
+// with 503 while down is set.
+func newProbeServer(t *testing.T, uin int64, down *atomic.Bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, EndpointGetLoginInfo):
+			fmt.Fprintf(w, `{"status":"ok","retcode":0,"data":{"uin":%d,"nickname":"bot"}}`, uin)
+		case strings.HasSuffix(r.URL.Path, EndpointGetImplInfo):
+			fmt.Fprint(w, `{"status":"ok","retcode":0,"data":{"impl_name":"test","milky_version":"1.0"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFailover(t *testing.T) {
+	var primaryDown, backupDown, otherDown atomic.Bool
+	primary := newProbeServer(t, 1001, &primaryDown)
+	other := newProbeServer(t, 2002, &otherDown)
+	backup := newProbeServer(t, 1001, &backupDown)
+
+	s, _ := New("", primary.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.SyncEvents = true
+	var switches []*GatewaySwitch
+	s.AddHandler(func(s *Session, e *GatewaySwitch) { switches = append(switches, e) })
+
+	f := NewFailover(s,
+		GatewayPair{RestGateway: primary.URL},
+		GatewayPair{RestGateway: other.URL},
+		GatewayPair{RestGateway: backup.URL},
+	)
+	f.FailureThreshold = 2
+	f.FailbackThreshold = 2
+	ctx := context.Background()
+
+	f.Check(ctx)
+	if f.Current() != 0 || f.SelfID != 1001 || len(switches) != 0 {
+		t.Fatalf("healthy primary: current %d, self %d, switches %d", f.Current(), f.SelfID, len(switches))
+	}
+
+	primaryDown.Store(true)
+	f.Check(ctx)
+	if f.Current() != 0 {
+		t.Fatalf("failed over before reaching the threshold")
+	}
+	f.Check(ctx)
+	// The second gateway is logged in to another account and is skipped.
+	if f.Current() != 2 || len(switches) != 1 {
+		t.Fatalf("after failures: current %d, switches %d", f.Current(), len(switches))
+	}
+	if e := switches[0]; e.From != 0 || e.To != 2 || e.Failback || e.Impl == nil || e.Impl.ImplName != "test" {
+		t.Errorf("unexpected switch event %+v", e)
+	}
+	if s.RestGateway != backup.URL || !strings.HasPrefix(s.endpoint(EndpointGetLoginInfo), backup.URL) {
+		t.Errorf("session still on %s", s.RestGateway)
+	}
+
+	primaryDown.Store(false)
+	f.Check(ctx)
+	if f.Current() != 2 || len(switches) != 1 {
+		t.Fatalf("failed back after one healthy check: current %d", f.Current())
+	}
+	f.Check(ctx)
+	if f.Current() != 0 || len(switches) != 2 || !switches[1].Failback {
+		t.Fatalf("after recovery: current %d, switches %+v", f.Current(), switches)
+	}
+	if _, err := s.GetLoginInfo(); err != nil {
+		t.Errorf("request after failback: %v", err)
+	}
+}
+
+func TestFailoverWrapsAround(t *testing.T) {
+	var down [3]atomic.Bool
+	var gateways []GatewayPair
+	for i := range down {
+		gateways = append(gateways, GatewayPair{RestGateway: newProbeServer(t, 1001, &down[i]).URL})
+	}
+
+	s, _ := New("", gateways[0].RestGateway, "", &TestLogger{})
+	s.LogLevel = LogError
+	f := NewFailover(s, gateways...)
+	f.FailureThreshold = 1
+	f.FailbackThreshold = 100
+	ctx := context.Background()
+
+	down[0].Store(true)
+	f.Check(ctx)
+	f.Check(ctx)
+	if f.Current() != 1 {
+		t.Fatalf("expected the second gateway, got %d", f.Current())
+	}
+
+	// The primary is healthy again but not yet trusted; the scan continues
+	// after the failed gateway rather than restarting at the primary.
+	down[0].Store(false)
+	down[1].Store(true)
+	f.Check(ctx)
+	if f.Current() != 2 {
+		t.Fatalf("expected the third gateway, got %d", f.Current())
+	}
+
+	down[2].Store(true)
+	f.Check(ctx)
+	if f.Current() != 0 {
+		t.Fatalf("expected to wrap around to the primary, got %d", f.Current())
+	}
+}
+
+func TestFailoverWakesReconnect(t *testing.T) {
+	var up atomic.Bool
+	primary := newProbeServer(t, 1001, &up)
+	backup := newProbeServer(t, 1001, &up)
+
+	s, _ := New("", primary.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	wake := make(chan struct{}, 1)
+	s.reconnectWake = wake
+
+	f := NewFailover(s, GatewayPair{RestGateway: primary.URL}, GatewayPair{RestGateway: backup.URL})
+	f.Check(context.Background())
+	f.mu.Lock()
+	f.switchTo(1, nil, false, "test")
+	f.mu.Unlock()
+
+	select {
+	case <-wake:
+	default:
+		t.Fatal("switching gateways while disconnected did not wake the reconnect loop")
+	}
+}
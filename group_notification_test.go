@@ -0,0 +1,102 @@
+package Milky_go_sdk
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+func TestUnmarshalGroupNotificationGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "group_notifications", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no group notification fixtures found")
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notification, err := UnmarshalGroupNotification(data)
+			if err != nil {
+				t.Fatalf("UnmarshalGroupNotification: %v", err)
+			}
+			decoded, err := json.MarshalIndent(notification, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fmt.Sprintf("%T\n%s\n", notification, decoded)
+
+			golden := strings.TrimSuffix(input, ".json") + ".golden"
+			if *updateGolden {
+				if err = os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("decoded %s mismatch\n got: %s\nwant: %s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestGroupNotificationActions(t *testing.T) {
+	var got []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		req["endpoint"] = strings.TrimPrefix(r.URL.Path, "/")
+		got = append(got, req)
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok"})
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	data, err := os.ReadFile(filepath.Join("testdata", "group_notifications", "join_request.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err := UnmarshalGroupNotification(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actionable, ok := notification.(ActionableGroupNotification)
+	if !ok {
+		t.Fatalf("%T is not actionable", notification)
+	}
+	if err = actionable.Accept(s); err != nil {
+		t.Fatal(err)
+	}
+	if err = actionable.Reject(s, "spam"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(got))
+	}
+	accept, reject := got[0], got[1]
+	if accept["endpoint"] != EndpointAcceptGroupRequest || accept["notification_seq"] != float64(1001) ||
+		accept["notification_type"] != "join_request" || accept["is_filtered"] != true {
+		t.Errorf("unexpected accept call %v", accept)
+	}
+	if reject["endpoint"] != EndpointRejectGroupRequest || reject["reason"] != "spam" || reject["group_id"] != float64(123456) {
+		t.Errorf("unexpected reject call %v", reject)
+	}
+}
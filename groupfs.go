@@ -0,0 +1,687 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RootFolderID is the folder ID of a group's root file folder.
+const RootFolderID = "/"
+
+// All group file system error constants
+var (
+	ErrGroupFileNotFound = errors.New("group file or folder not found")
+	ErrGroupFolderDepth  = errors.New("group folders can only be created directly under the root folder")
+	ErrIsGroupFolder     = errors.New("path is a group folder")
+	ErrNotGroupFolder    = errors.New("path is not a group folder")
+)
+
+// GroupFSEntry is a file or a folder in a group's file tree. Exactly one of
+// File and Folder is set, except for the root folder where both are nil.
+type GroupFSEntry struct {
+	Path   string // slash separated, rooted at "/"
+	File   *GroupFile
+	Folder *GroupFolder
+}
+
+// IsDir reports whether the entry is a folder.
+func (e *GroupFSEntry) IsDir() bool {
+	return e.File == nil
+}
+
+// ID returns the file ID or folder ID of the entry.
+func (e *GroupFSEntry) ID() string {
+	switch {
+	case e.File != nil:
+		return e.File.FileID
+	case e.Folder != nil:
+		return e.Folder.FolderID
+	default:
+		return RootFolderID
+	}
+}
+
+// ParentFolderID returns the ID of the folder containing the entry.
+func (e *GroupFSEntry) ParentFolderID() string {
+	var parent string
+	switch {
+	case e.File != nil:
+		parent = e.File.ParentFolderID
+	case e.Folder != nil:
+		parent = e.Folder.ParentFolderID
+	}
+	if parent == "" {
+		return RootFolderID
+	}
+	return parent
+}
+
+// GroupFSWalkFunc is called by GroupFS.Walk for every visited entry. Returning
+// fs.SkipDir from a folder skips its contents, fs.SkipAll stops the walk.
+type GroupFSWalkFunc func(entry *GroupFSEntry, err error) error
+
+// SyncReport lists what a GroupFS sync changed, by path.
+type SyncReport struct {
+	Uploaded   []string
+	Downloaded []string
+	Deleted    []string
+	Skipped    []string
+}
+
+// GroupFS is a path based client for a group's files. It also implements
+// io/fs.FS, where names are the unrooted form of group paths.
+type GroupFS struct {
+	Session *Session
+	GroupID int64
+
+	// The http client used to download file content.
+	Client *http.Client
+
+	// Context used by the io/fs.FS methods, which cannot take one.
+	Context context.Context
+
+	// LocalFileURI turns a local file path into the file_uri passed to
+	// UploadGroupFile. It defaults to a base64:// URI, which works against
+	// remote Milky implementations; set it to produce file:// URIs when the
+	// implementation shares the file system.
+	LocalFileURI func(localPath string) (string, error)
+}
+
+// NewGroupFS returns a GroupFS for groupID.
+func NewGroupFS(s *Session, groupID int64) *GroupFS {
+	return &GroupFS{
+		Session:      s,
+		GroupID:      groupID,
+		Client:       &http.Client{},
+		Context:      context.Background(),
+		LocalFileURI: base64FileURI,
+	}
+}
+
+// List returns the entries directly inside the folder at dir.
+func (g *GroupFS) List(ctx context.Context, dir string) ([]*GroupFSEntry, error) {
+	parent, err := g.Stat(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsDir() {
+		return nil, ErrNotGroupFolder
+	}
+	return g.list(ctx, parent)
+}
+
+// Stat resolves a path such as "/docs/report.pdf" to its entry.
+func (g *GroupFS) Stat(ctx context.Context, name string) (*GroupFSEntry, error) {
+	entry := &GroupFSEntry{Path: "/"}
+	for _, part := range splitGroupPath(name) {
+		if !entry.IsDir() {
+			return nil, fmt.Errorf("%w: %s", ErrGroupFileNotFound, name)
+		}
+		children, err := g.list(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		var next *GroupFSEntry
+		for _, child := range children {
+			if path.Base(child.Path) == part {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("%w: %s", ErrGroupFileNotFound, name)
+		}
+		entry = next
+	}
+	return entry, nil
+}
+
+// Walk visits every entry below root, root included, folders before their contents.
+func (g *GroupFS) Walk(ctx context.Context, root string, fn GroupFSWalkFunc) error {
+	entry, err := g.Stat(ctx, root)
+	if err != nil {
+		err = fn(&GroupFSEntry{Path: cleanGroupPath(root)}, err)
+	} else {
+		err = g.walk(ctx, entry, fn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (g *GroupFS) walk(ctx context.Context, entry *GroupFSEntry, fn GroupFSWalkFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(entry, nil); err != nil || !entry.IsDir() {
+		return err
+	}
+	children, err := g.list(ctx, entry)
+	if err != nil {
+		return fn(entry, err)
+	}
+	for _, child := range children {
+		if err = g.walk(ctx, child, fn); err != nil {
+			if errors.Is(err, fs.SkipDir) && child.IsDir() {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirAll makes sure the folder at dir exists and returns its ID. Group
+// folders cannot be nested, so dir may be at most one level deep.
+func (g *GroupFS) MkdirAll(ctx context.Context, dir string) (string, error) {
+	parts := splitGroupPath(dir)
+	if len(parts) == 0 {
+		return RootFolderID, nil
+	}
+	if len(parts) > 1 {
+		return "", fmt.Errorf("%w: %s", ErrGroupFolderDepth, dir)
+	}
+	entry, err := g.Stat(ctx, dir)
+	if err == nil {
+		if !entry.IsDir() {
+			return "", fmt.Errorf("%w: %s", ErrNotGroupFolder, dir)
+		}
+		return entry.ID(), nil
+	}
+	if !errors.Is(err, ErrGroupFileNotFound) {
+		return "", err
+	}
+	return g.Session.CreateGroupFolder(g.GroupID, parts[0], WithContext(ctx))
+}
+
+// Remove deletes the file at name, or the folder at name if it is empty.
+func (g *GroupFS) Remove(ctx context.Context, name string) error {
+	entry, err := g.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !entry.IsDir() {
+		return g.Session.DeleteGroupFile(g.GroupID, entry.ID(), WithContext(ctx))
+	}
+	children, err := g.list(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("group folder %s is not empty", entry.Path)
+	}
+	return g.Session.DeleteGroupFolder(g.GroupID, entry.ID(), WithContext(ctx))
+}
+
+// RemoveAll deletes name and everything below it. It does nothing if name
+// does not exist. The root folder itself is never deleted, only emptied.
+func (g *GroupFS) RemoveAll(ctx context.Context, name string) error {
+	entry, err := g.Stat(ctx, name)
+	if errors.Is(err, ErrGroupFileNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return g.removeAll(ctx, entry)
+}
+
+func (g *GroupFS) removeAll(ctx context.Context, entry *GroupFSEntry) error {
+	if !entry.IsDir() {
+		return g.Session.DeleteGroupFile(g.GroupID, entry.ID(), WithContext(ctx))
+	}
+	children, err := g.list(ctx, entry)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err = g.removeAll(ctx, child); err != nil {
+			return err
+		}
+	}
+	if entry.Folder == nil {
+		return nil
+	}
+	return g.Session.DeleteGroupFolder(g.GroupID, entry.ID(), WithContext(ctx))
+}
+
+// Rename renames the file or folder at name to newName, keeping it in place.
+func (g *GroupFS) Rename(ctx context.Context, name string, newName string) error {
+	entry, err := g.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return g.Session.RenameGroupFolder(g.GroupID, entry.ID(), newName, WithContext(ctx))
+	}
+	return g.Session.RenameGroupFile(g.GroupID, entry.ID(), entry.ParentFolderID(), newName, WithContext(ctx))
+}
+
+// Move moves the file at name into the folder at targetDir.
+func (g *GroupFS) Move(ctx context.Context, name string, targetDir string) error {
+	entry, err := g.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return fmt.Errorf("%w: %s", ErrIsGroupFolder, name)
+	}
+	target, err := g.Stat(ctx, targetDir)
+	if err != nil {
+		return err
+	}
+	if !target.IsDir() {
+		return fmt.Errorf("%w: %s", ErrNotGroupFolder, targetDir)
+	}
+	return g.Session.MoveGroupFile(g.GroupID, entry.ID(), entry.ParentFolderID(), target.ID(), WithContext(ctx))
+}
+
+// Upload uploads the local file at localPath to remotePath, creating the
+// containing folder if needed. It returns the new file ID.
+func (g *GroupFS) Upload(ctx context.Context, localPath string, remotePath string) (string, error) {
+	folderID, err := g.MkdirAll(ctx, path.Dir(cleanGroupPath(remotePath)))
+	if err != nil {
+		return "", err
+	}
+	uri, err := g.LocalFileURI(localPath)
+	if err != nil {
+		return "", err
+	}
+	return g.Session.UploadGroupFile(g.GroupID, uri, path.Base(cleanGroupPath(remotePath)), folderID, WithContext(ctx))
+}
+
+// Download streams the file at name to w.
+func (g *GroupFS) Download(ctx context.Context, name string, w io.Writer) (int64, error) {
+	entry, err := g.Stat(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if entry.IsDir() {
+		return 0, fmt.Errorf("%w: %s", ErrIsGroupFolder, name)
+	}
+	body, err := g.openFile(ctx, entry)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
+// SyncUp mirrors the local directory localDir into remoteDir. Files are
+// uploaded when missing remotely or when their size differs. With
+// deleteExtra, remote files that have no local counterpart are deleted.
+func (g *GroupFS) SyncUp(ctx context.Context, localDir string, remoteDir string, deleteExtra bool) (*SyncReport, error) {
+	report := &SyncReport{}
+	remote, err := g.index(ctx, remoteDir)
+	if err != nil && !errors.Is(err, ErrGroupFileNotFound) {
+		return report, err
+	}
+
+	seen := map[string]bool{}
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(cleanGroupPath(remoteDir), filepath.ToSlash(rel))
+		seen[remotePath] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		existing, ok := remote[remotePath]
+		if ok && existing.File.FileSize == info.Size() {
+			report.Skipped = append(report.Skipped, remotePath)
+			return nil
+		}
+		if _, err = g.Upload(ctx, p, remotePath); err != nil {
+			return err
+		}
+		report.Uploaded = append(report.Uploaded, remotePath)
+		// The outdated copy is only deleted once its replacement is uploaded.
+		if ok {
+			return g.Session.DeleteGroupFile(g.GroupID, existing.ID(), WithContext(ctx))
+		}
+		return nil
+	})
+	if err != nil || !deleteExtra {
+		return report, err
+	}
+
+	for remotePath, entry := range remote {
+		if seen[remotePath] {
+			continue
+		}
+		if err = g.Session.DeleteGroupFile(g.GroupID, entry.ID(), WithContext(ctx)); err != nil {
+			return report, err
+		}
+		report.Deleted = append(report.Deleted, remotePath)
+	}
+	return report, nil
+}
+
+// SyncDown mirrors remoteDir into the local directory localDir. Files are
+// downloaded when missing locally or when their size differs. With
+// deleteExtra, local files that have no remote counterpart are deleted.
+func (g *GroupFS) SyncDown(ctx context.Context, remoteDir string, localDir string, deleteExtra bool) (*SyncReport, error) {
+	report := &SyncReport{}
+	remote, err := g.index(ctx, remoteDir)
+	if err != nil {
+		return report, err
+	}
+
+	seen := map[string]bool{}
+	for remotePath, entry := range remote {
+		rel := strings.TrimPrefix(strings.TrimPrefix(remotePath, cleanGroupPath(remoteDir)), "/")
+		localPath := filepath.Join(localDir, filepath.FromSlash(rel))
+		seen[localPath] = true
+
+		if info, err := os.Stat(localPath); err == nil && info.Size() == entry.File.FileSize {
+			report.Skipped = append(report.Skipped, remotePath)
+			continue
+		}
+		if err = g.downloadTo(ctx, entry, localPath); err != nil {
+			return report, err
+		}
+		report.Downloaded = append(report.Downloaded, remotePath)
+	}
+	if !deleteExtra {
+		return report, nil
+	}
+
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || seen[p] {
+			return err
+		}
+		if err = os.Remove(p); err != nil {
+			return err
+		}
+		report.Deleted = append(report.Deleted, p)
+		return nil
+	})
+	return report, err
+}
+
+func (g *GroupFS) downloadTo(ctx context.Context, entry *GroupFSEntry, localPath string) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return err
+	}
+	body, err := g.openFile(ctx, entry)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		_ = os.Remove(localPath)
+	}
+	return err
+}
+
+// index returns every file below dir keyed by path.
+func (g *GroupFS) index(ctx context.Context, dir string) (map[string]*GroupFSEntry, error) {
+	files := map[string]*GroupFSEntry{}
+	err := g.Walk(ctx, dir, func(entry *GroupFSEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			files[entry.Path] = entry
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (g *GroupFS) list(ctx context.Context, dir *GroupFSEntry) ([]*GroupFSEntry, error) {
+	files, folders, err := g.Session.GetGroupFiles(g.GroupID, dir.ID(), WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*GroupFSEntry, 0, len(folders)+len(files))
+	for i := range folders {
+		entries = append(entries, &GroupFSEntry{Path: path.Join(dir.Path, folders[i].FolderName), Folder: &folders[i]})
+	}
+	for i := range files {
+		entries = append(entries, &GroupFSEntry{Path: path.Join(dir.Path, files[i].FileName), File: &files[i]})
+	}
+	return entries, nil
+}
+
+func (g *GroupFS) openFile(ctx context.Context, entry *GroupFSEntry) (io.ReadCloser, error) {
+	url, err := g.Session.GetGroupFileDownloadURL(g.GroupID, entry.ID(), WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("downloading group file %s: HTTP %s", entry.Path, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Open implements io/fs.FS.
+func (g *GroupFS) Open(name string) (fs.File, error) {
+	entry, err := g.fsStat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &groupFSFile{g: g, entry: entry}, nil
+}
+
+// fsStat resolves an io/fs name, wrapping failures in *fs.PathError.
+func (g *GroupFS) fsStat(op string, name string) (*GroupFSEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	entry, err := g.Stat(g.context(), name)
+	if errors.Is(err, ErrGroupFileNotFound) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return entry, nil
+}
+
+// ReadDir implements io/fs.ReadDirFS.
+func (g *GroupFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := g.fsStat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: ErrNotGroupFolder}
+	}
+	children, err := g.list(g.context(), entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	dirEntries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		dirEntries[i] = groupFileInfo{child}
+	}
+	sort.Slice(dirEntries, func(i, j int) bool { return dirEntries[i].Name() < dirEntries[j].Name() })
+	return dirEntries, nil
+}
+
+func (g *GroupFS) context() context.Context {
+	if g.Context == nil {
+		return context.Background()
+	}
+	return g.Context
+}
+
+// groupFSFile is the io/fs.File returned by GroupFS.Open. File content is
+// only fetched on the first Read.
+type groupFSFile struct {
+	g       *GroupFS
+	entry   *GroupFSEntry
+	body    io.ReadCloser
+	entries []fs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+func (f *groupFSFile) Stat() (fs.FileInfo, error) {
+	return groupFileInfo{f.entry}, nil
+}
+
+func (f *groupFSFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.entry.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.Path, Err: ErrIsGroupFolder}
+	}
+	if f.body == nil {
+		body, err := f.g.openFile(f.g.context(), f.entry)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.entry.Path, Err: err}
+		}
+		f.body = body
+	}
+	return f.body.Read(p)
+}
+
+func (f *groupFSFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.entry.Path, Err: ErrNotGroupFolder}
+	}
+	if !f.listed {
+		children, err := f.g.list(f.g.context(), f.entry)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: f.entry.Path, Err: err}
+		}
+		for _, child := range children {
+			f.entries = append(f.entries, groupFileInfo{child})
+		}
+		f.listed = true
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.entries) {
+		n = len(f.entries)
+	}
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *groupFSFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+// groupFileInfo adapts a GroupFSEntry to io/fs.FileInfo and io/fs.DirEntry.
+type groupFileInfo struct {
+	entry *GroupFSEntry
+}
+
+func (i groupFileInfo) Name() string {
+	if i.entry.Path == "/" {
+		return "."
+	}
+	return path.Base(i.entry.Path)
+}
+
+func (i groupFileInfo) Size() int64 {
+	if i.entry.File != nil {
+		return i.entry.File.FileSize
+	}
+	return 0
+}
+
+func (i groupFileInfo) Mode() fs.FileMode {
+	if i.entry.IsDir() {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (i groupFileInfo) ModTime() time.Time {
+	switch {
+	case i.entry.File != nil:
+		return time.Unix(i.entry.File.UploadedTime, 0)
+	case i.entry.Folder != nil:
+		return time.Unix(i.entry.Folder.LastModifiedTime, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+func (i groupFileInfo) IsDir() bool                { return i.entry.IsDir() }
+func (i groupFileInfo) Sys() interface{}           { return i.entry }
+func (i groupFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i groupFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// cleanGroupPath returns the rooted, cleaned form of a group path.
+func cleanGroupPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// splitGroupPath splits a group path into its components, "." and "/" being the root.
+func splitGroupPath(name string) []string {
+	cleaned := strings.Trim(cleanGroupPath(name), "/")
+	if cleaned == "" {
+		return nil
+	}
+	return strings.Split(cleaned, "/")
+}
+
+// base64FileURI reads a local file into a base64:// URI.
+func base64FileURI(localPath string) (string, error) {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	return "base64://" + base64.StdEncoding.EncodeToString(data), nil
+}
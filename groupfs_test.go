@@ -0,0 +1,353 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGroupFS(t *testing.T) {
+	contents := map[string]string{
+		"f-readme":  "hello group",
+		"f-report":  "quarterly numbers",
+		"f-zeta":    "last",
+		"f-archive": "first",
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		var data interface{}
+		switch r.URL.Path {
+		case "/" + EndpointGetGroupFiles:
+			switch req["parent_folder_id"] {
+			case RootFolderID:
+				data = map[string]interface{}{
+					"files": []GroupFile{
+						{GroupId: 1, FileID: "f-zeta", FileName: "zeta.txt", FileSize: 4},
+						{GroupId: 1, FileID: "f-readme", FileName: "readme.txt", FileSize: 11},
+						{GroupId: 1, FileID: "f-archive", FileName: "archive.zip", FileSize: 5},
+					},
+					"folders": []GroupFolder{{GroupId: 1, FolderID: "d-docs", FolderName: "docs"}},
+				}
+			case "d-docs":
+				data = map[string]interface{}{
+					"files":   []GroupFile{{GroupId: 1, FileID: "f-report", FileName: "report.pdf", ParentFolderID: "d-docs", FileSize: 17}},
+					"folders": []GroupFolder{},
+				}
+			}
+		case "/" + EndpointGetGroupFileDownloadURL:
+			data = map[string]string{"download_url": server.URL + "/content/" + req["file_id"].(string)}
+		default:
+			if content, ok := contents[r.URL.Path[len("/content/"):]]; ok {
+				_, _ = w.Write([]byte(content))
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+		raw, _ := json.Marshal(data)
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: raw})
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	g := NewGroupFS(s, 1)
+
+	entry, err := g.Stat(context.Background(), "/docs/report.pdf")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if entry.ID() != "f-report" || entry.ParentFolderID() != "d-docs" {
+		t.Fatalf("resolved %+v, want f-report in d-docs", entry)
+	}
+	if _, err = g.Stat(context.Background(), "/docs/missing.pdf"); !errors.Is(err, ErrGroupFileNotFound) {
+		t.Fatalf("expected ErrGroupFileNotFound, got %v", err)
+	}
+	if _, err = g.MkdirAll(context.Background(), "/docs/2025"); !errors.Is(err, ErrGroupFolderDepth) {
+		t.Fatalf("expected ErrGroupFolderDepth, got %v", err)
+	}
+
+	var walked []string
+	err = g.Walk(context.Background(), "/", func(entry *GroupFSEntry, err error) error {
+		walked = append(walked, entry.Path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(walked) != 6 {
+		t.Fatalf("walked %v, want 6 entries", walked)
+	}
+
+	dirEntries, err := g.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range dirEntries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"archive.zip", "docs", "readme.txt", "zeta.txt"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("ReadDir returned %v, want %v", names, want)
+	}
+
+	if err = fstest.TestFS(g, "readme.txt", "zeta.txt", "archive.zip", "docs/report.pdf"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeGroupDrive is a stateful group file server for sync tests.
+type fakeGroupDrive struct {
+	t       *testing.T
+	server  *httptest.Server
+	mu      sync.Mutex
+	nextID  int
+	folders map[string]string // folder ID to name
+	files   map[string]GroupFile
+	content map[string][]byte
+	calls   []string
+	failOn  string // endpoint that fails with retcode 1
+}
+
+func newFakeGroupDrive(t *testing.T) *fakeGroupDrive {
+	d := &fakeGroupDrive{t: t, folders: map[string]string{}, files: map[string]GroupFile{}, content: map[string][]byte{}}
+	d.server = httptest.NewServer(http.HandlerFunc(d.serve))
+	t.Cleanup(d.server.Close)
+	return d
+}
+
+func (d *fakeGroupDrive) put(folderID string, name string, content string) string {
+	d.nextID++
+	id := fmt.Sprintf("f-%d", d.nextID)
+	d.files[id] = GroupFile{GroupId: 1, FileID: id, FileName: name, ParentFolderID: folderID, FileSize: int64(len(content))}
+	d.content[id] = []byte(content)
+	return id
+}
+
+func (d *fakeGroupDrive) mkdir(name string) string {
+	d.nextID++
+	id := fmt.Sprintf("d-%d", d.nextID)
+	d.folders[id] = name
+	return id
+}
+
+func (d *fakeGroupDrive) serve(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/content/") {
+		content, ok := d.content[strings.TrimPrefix(r.URL.Path, "/content/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+		return
+	}
+
+	var req map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	endpoint := strings.TrimPrefix(r.URL.Path, "/")
+	d.calls = append(d.calls, endpoint)
+	if endpoint == d.failOn {
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "failed", RetCode: 1, Message: "injected failure"})
+		return
+	}
+
+	var data interface{}
+	switch endpoint {
+	case EndpointGetGroupFiles:
+		parent := req["parent_folder_id"].(string)
+		files, folders := []GroupFile{}, []GroupFolder{}
+		for _, f := range d.files {
+			if f.ParentFolderID == parent {
+				files = append(files, f)
+			}
+		}
+		if parent == RootFolderID {
+			for id, name := range d.folders {
+				folders = append(folders, GroupFolder{GroupId: 1, FolderID: id, FolderName: name, ParentFolderID: RootFolderID})
+			}
+		}
+		data = map[string]interface{}{"files": files, "folders": folders}
+	case EndpointGetGroupFileDownloadURL:
+		data = map[string]string{"download_url": d.server.URL + "/content/" + req["file_id"].(string)}
+	case EndpointUploadGroupFile:
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(req["file_uri"].(string), "base64://"))
+		if err != nil {
+			d.t.Errorf("bad file_uri: %v", err)
+		}
+		data = map[string]string{"file_id": d.put(req["parent_folder_id"].(string), req["file_name"].(string), string(raw))}
+	case EndpointCreateGroupFolder:
+		data = map[string]string{"folder_id": d.mkdir(req["folder_name"].(string))}
+	case EndpointDeleteGroupFile:
+		delete(d.files, req["file_id"].(string))
+	case EndpointDeleteGroupFolder:
+		id := req["folder_id"].(string)
+		for _, f := range d.files {
+			if f.ParentFolderID == id {
+				d.t.Errorf("deleted folder %s before its file %s", id, f.FileName)
+			}
+		}
+		delete(d.folders, id)
+	default:
+		d.t.Errorf("unexpected endpoint %s", endpoint)
+	}
+	raw, _ := json.Marshal(data)
+	_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: raw})
+}
+
+func writeLocalFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGroupFSSyncUp(t *testing.T) {
+	d := newFakeGroupDrive(t)
+	same := d.put(RootFolderID, "same.txt", "unchanged")
+	changed := d.put(RootFolderID, "changed.txt", "old")
+	extra := d.put(RootFolderID, "extra.txt", "stale")
+
+	local := t.TempDir()
+	writeLocalFiles(t, local, map[string]string{
+		"same.txt":     "unchanged",
+		"changed.txt":  "new content",
+		"docs/new.txt": "brand new",
+	})
+
+	s, _ := New("", d.server.URL, "", &TestLogger{})
+	g := NewGroupFS(s, 1)
+
+	report, err := g.SyncUp(context.Background(), local, "/", true)
+	if err != nil {
+		t.Fatalf("SyncUp: %v", err)
+	}
+	sort.Strings(report.Uploaded)
+	if want := []string{"/changed.txt", "/docs/new.txt"}; !reflect.DeepEqual(report.Uploaded, want) {
+		t.Fatalf("uploaded %v, want %v", report.Uploaded, want)
+	}
+	if !reflect.DeepEqual(report.Skipped, []string{"/same.txt"}) || !reflect.DeepEqual(report.Deleted, []string{"/extra.txt"}) {
+		t.Fatalf("skipped %v and deleted %v", report.Skipped, report.Deleted)
+	}
+	if _, ok := d.files[same]; !ok {
+		t.Fatal("unchanged file was replaced")
+	}
+	if _, ok := d.files[changed]; ok {
+		t.Fatal("outdated copy of changed.txt was not deleted")
+	}
+	if _, ok := d.files[extra]; ok {
+		t.Fatal("extra.txt was not deleted")
+	}
+	entry, err := g.Stat(context.Background(), "/changed.txt")
+	if err != nil || string(d.content[entry.ID()]) != "new content" {
+		t.Fatalf("changed.txt not replaced: %+v, %v", entry, err)
+	}
+}
+
+func TestGroupFSSyncUpKeepsRemoteOnUploadFailure(t *testing.T) {
+	d := newFakeGroupDrive(t)
+	changed := d.put(RootFolderID, "changed.txt", "old")
+	d.failOn = EndpointUploadGroupFile
+
+	local := t.TempDir()
+	writeLocalFiles(t, local, map[string]string{"changed.txt": "new content"})
+
+	s, _ := New("", d.server.URL, "", &TestLogger{})
+	if _, err := NewGroupFS(s, 1).SyncUp(context.Background(), local, "/", false); err == nil {
+		t.Fatal("expected the upload failure to be returned")
+	}
+	if _, ok := d.files[changed]; !ok {
+		t.Fatal("remote copy was deleted although the upload failed")
+	}
+	for _, call := range d.calls {
+		if call == EndpointDeleteGroupFile {
+			t.Fatal("delete_group_file called before a successful upload")
+		}
+	}
+}
+
+func TestGroupFSSyncDown(t *testing.T) {
+	d := newFakeGroupDrive(t)
+	docs := d.mkdir("docs")
+	d.put(RootFolderID, "same.txt", "unchanged")
+	d.put(RootFolderID, "changed.txt", "new content")
+	d.put(docs, "report.pdf", "quarterly")
+
+	local := t.TempDir()
+	writeLocalFiles(t, local, map[string]string{
+		"same.txt":    "unchanged",
+		"changed.txt": "old",
+		"extra.txt":   "stale",
+	})
+
+	s, _ := New("", d.server.URL, "", &TestLogger{})
+	report, err := NewGroupFS(s, 1).SyncDown(context.Background(), "/", local, true)
+	if err != nil {
+		t.Fatalf("SyncDown: %v", err)
+	}
+	sort.Strings(report.Downloaded)
+	if want := []string{"/changed.txt", "/docs/report.pdf"}; !reflect.DeepEqual(report.Downloaded, want) {
+		t.Fatalf("downloaded %v, want %v", report.Downloaded, want)
+	}
+	if !reflect.DeepEqual(report.Skipped, []string{"/same.txt"}) {
+		t.Fatalf("skipped %v", report.Skipped)
+	}
+	if !reflect.DeepEqual(report.Deleted, []string{filepath.Join(local, "extra.txt")}) {
+		t.Fatalf("deleted %v", report.Deleted)
+	}
+	for name, want := range map[string]string{"changed.txt": "new content", "docs/report.pdf": "quarterly"} {
+		got, err := os.ReadFile(filepath.Join(local, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Fatalf("%s = %q (%v), want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestGroupFSRemoveAll(t *testing.T) {
+	d := newFakeGroupDrive(t)
+	docs := d.mkdir("docs")
+	d.put(docs, "a.txt", "a")
+	d.put(docs, "b.txt", "b")
+	keep := d.put(RootFolderID, "keep.txt", "keep")
+
+	s, _ := New("", d.server.URL, "", &TestLogger{})
+	g := NewGroupFS(s, 1)
+
+	if err := g.RemoveAll(context.Background(), "/docs"); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	if len(d.folders) != 0 || len(d.files) != 1 {
+		t.Fatalf("left folders %v and files %v, want only keep.txt", d.folders, d.files)
+	}
+	if err := g.RemoveAll(context.Background(), "/docs"); err != nil {
+		t.Fatalf("RemoveAll of a missing path: %v", err)
+	}
+	if err := g.RemoveAll(context.Background(), "/"); err != nil {
+		t.Fatalf("RemoveAll of the root: %v", err)
+	}
+	if _, ok := d.files[keep]; ok || len(d.files) != 0 {
+		t.Fatalf("root was not emptied: %v", d.files)
+	}
+}
@@ -0,0 +1,165 @@
+package Milky_go_sdk
+
+import "time"
+
+// GroupHandle calls the group API for one group. It holds no state besides
+// the IDs and is cheap to create, see Session.Group.
+type GroupHandle struct {
+	Session *Session
+	ID      int64
+}
+
+// Group returns a handle on the group groupID.
+func (s *Session) Group(groupID int64) *GroupHandle {
+	return &GroupHandle{Session: s, ID: groupID}
+}
+
+// Info returns the group's information.
+func (g *GroupHandle) Info(noCache bool, options ...RequestOption) (*GroupInfo, error) {
+	return g.Session.GetGroupInfo(g.ID, noCache, options...)
+}
+
+// Send sends a message to the group.
+func (g *GroupHandle) Send(message []IMessageElement, options ...RequestOption) (*MessageRet, error) {
+	return g.Session.SendGroupMessage(g.ID, &message, options...)
+}
+
+// Recall recalls a message sent in the group.
+func (g *GroupHandle) Recall(messageSeq int64, options ...RequestOption) error {
+	return g.Session.RecallGroupMessage(g.ID, messageSeq, options...)
+}
+
+// History returns an iterator over the group's messages, newest first.
+func (g *GroupHandle) History(options ...IteratorOption) *HistoryIterator {
+	return NewHistoryIterator(g.Session, SceneGroup, g.ID, 0, options...)
+}
+
+// Members returns the group's members.
+func (g *GroupHandle) Members(noCache bool, options ...RequestOption) ([]GroupMemberInfo, error) {
+	return g.Session.GetGroupMemberList(g.ID, noCache, options...)
+}
+
+// Member returns a handle on the member userID of the group.
+func (g *GroupHandle) Member(userID int64) *MemberHandle {
+	return &MemberHandle{Group: g, ID: userID}
+}
+
+// Files returns the group's file system.
+func (g *GroupHandle) Files() *GroupFS {
+	return NewGroupFS(g.Session, g.ID)
+}
+
+// Announcements returns the group's announcements.
+func (g *GroupHandle) Announcements(options ...RequestOption) ([]GroupAnnouncement, error) {
+	return g.Session.GetGroupAnnouncementList(g.ID, options...)
+}
+
+// Essence returns an iterator over the group's essence messages.
+func (g *GroupHandle) Essence(options ...IteratorOption) *EssenceIterator {
+	return NewEssenceIterator(g.Session, g.ID, options...)
+}
+
+// SetName renames the group.
+func (g *GroupHandle) SetName(name string, options ...RequestOption) error {
+	return g.Session.SetGroupName(g.ID, name, options...)
+}
+
+// MuteAll mutes or unmutes the whole group.
+func (g *GroupHandle) MuteAll(isMute bool, options ...RequestOption) error {
+	return g.Session.SetGroupWholeMute(g.ID, isMute, options...)
+}
+
+// Quit leaves the group.
+func (g *GroupHandle) Quit(options ...RequestOption) error {
+	return g.Session.QuitGroup(g.ID, options...)
+}
+
+// MemberHandle calls the group member API for one member, see
+// GroupHandle.Member.
+type MemberHandle struct {
+	Group *GroupHandle
+	ID    int64
+}
+
+// Info returns the member's information.
+func (m *MemberHandle) Info(noCache bool, options ...RequestOption) (*GroupMemberInfo, error) {
+	return m.Group.Session.GetGroupMemberInfo(m.Group.ID, m.ID, noCache, options...)
+}
+
+// Mute mutes the member for d, which is rounded up to whole seconds.
+func (m *MemberHandle) Mute(d time.Duration, options ...RequestOption) error {
+	return m.Group.Session.SetGroupMemberMuteDuration(m.Group.ID, m.ID, d, options...)
+}
+
+// Unmute lifts the member's mute.
+func (m *MemberHandle) Unmute(options ...RequestOption) error {
+	return m.Group.Session.SetGroupMemberMuteDuration(m.Group.ID, m.ID, 0, options...)
+}
+
+// Kick removes the member from the group. With rejectAddRequest their
+// future join requests are rejected.
+func (m *MemberHandle) Kick(rejectAddRequest bool, options ...RequestOption) error {
+	return m.Group.Session.KickGroupMember(m.Group.ID, m.ID, rejectAddRequest, options...)
+}
+
+// SetCard sets the member's group nickname.
+func (m *MemberHandle) SetCard(card string, options ...RequestOption) error {
+	return m.Group.Session.SetGroupMemberCard(m.Group.ID, m.ID, card, options...)
+}
+
+// SetTitle sets the member's special title.
+func (m *MemberHandle) SetTitle(title string, options ...RequestOption) error {
+	return m.Group.Session.SetGroupMemberSpecialTitle(m.Group.ID, m.ID, title, options...)
+}
+
+// SetAdmin grants or revokes the member's admin role.
+func (m *MemberHandle) SetAdmin(isSet bool, options ...RequestOption) error {
+	return m.Group.Session.SetGroupMemberAdmin(m.Group.ID, m.ID, isSet, options...)
+}
+
+// Nudge nudges the member in the group.
+func (m *MemberHandle) Nudge(options ...RequestOption) error {
+	return m.Group.Session.SendGroupNudge(m.Group.ID, m.ID, options...)
+}
+
+// FriendHandle calls the friend API for one friend, see Session.Friend.
+type FriendHandle struct {
+	Session *Session
+	ID      int64
+}
+
+// Friend returns a handle on the friend userID.
+func (s *Session) Friend(userID int64) *FriendHandle {
+	return &FriendHandle{Session: s, ID: userID}
+}
+
+// Info returns the friend's information.
+func (f *FriendHandle) Info(noCache bool, options ...RequestOption) (*Friend, error) {
+	return f.Session.GetFriendInfo(f.ID, noCache, options...)
+}
+
+// Send sends a private message to the friend.
+func (f *FriendHandle) Send(message []IMessageElement, options ...RequestOption) (*MessageRet, error) {
+	return f.Session.SendPrivateMessage(f.ID, &message, options...)
+}
+
+// Recall recalls a private message sent to the friend.
+func (f *FriendHandle) Recall(messageSeq int64, options ...RequestOption) error {
+	return f.Session.RecallPrivateMessage(f.ID, messageSeq, options...)
+}
+
+// Nudge nudges the friend.
+func (f *FriendHandle) Nudge(options ...RequestOption) error {
+	return f.Session.SendFriendNudge(f.ID, false, options...)
+}
+
+// Like likes the friend's profile count times.
+func (f *FriendHandle) Like(count int32, options ...RequestOption) error {
+	return f.Session.SendProfileLike(f.ID, count, options...)
+}
+
+// History returns an iterator over the conversation with the friend, newest
+// first.
+func (f *FriendHandle) History(options ...IteratorOption) *HistoryIterator {
+	return NewHistoryIterator(f.Session, SceneFriend, f.ID, 0, options...)
+}
@@ -0,0 +1,61 @@
+package Milky_go_sdk
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandles(t *testing.T) {
+	type call struct {
+		endpoint string
+		payload  map[string]interface{}
+	}
+	var calls []call
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		calls = append(calls, call{strings.TrimPrefix(r.URL.Path, "/"), payload})
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{}}`))
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	g := s.Group(100)
+	if _, err := g.Send([]IMessageElement{&TextElement{Text: "hi"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Member(7).Mute(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Member(7).SetCard("card"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Friend(8).Like(10); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []call{
+		{EndpointSendGroupMessage, map[string]interface{}{"group_id": 100.0}},
+		{EndpointSetGroupMemberMute, map[string]interface{}{"group_id": 100.0, "user_id": 7.0, "duration": 60.0}},
+		{EndpointSetGroupMemberCard, map[string]interface{}{"group_id": 100.0, "user_id": 7.0, "card": "card"}},
+		{EndpointSendProfileLike, map[string]interface{}{"user_id": 8.0, "count": 10.0}},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("got %d calls, want %d", len(calls), len(want))
+	}
+	for i, c := range calls {
+		if c.endpoint != want[i].endpoint {
+			t.Errorf("call %d: endpoint %s, want %s", i, c.endpoint, want[i].endpoint)
+		}
+		for k, v := range want[i].payload {
+			if c.payload[k] != v {
+				t.Errorf("call %d: %s = %v, want %v", i, k, c.payload[k], v)
+			}
+		}
+	}
+}
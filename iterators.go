@@ -0,0 +1,281 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultIteratorPageSize = 20
+	defaultIteratorPrefetch = 1
+)
+
+// iteratorConfig holds the settings shared by all paged API iterators.
+type iteratorConfig struct {
+	PageSize int32
+	Prefetch int
+	MinSeq   int64
+	Since    time.Time
+}
+
+// IteratorOption is a function which mutates iterator configuration.
+// It can be supplied as an argument to any iterator constructor.
+type IteratorOption func(cfg *iteratorConfig)
+
+// WithPageSize changes how many items are requested per API call.
+func WithPageSize(size int32) IteratorOption {
+	return func(cfg *iteratorConfig) {
+		if size > 0 {
+			cfg.PageSize = size
+		}
+	}
+}
+
+// WithPrefetch changes how many pages are fetched ahead in the background.
+// With 0 each page is fetched by Next when the previous one is used up.
+func WithPrefetch(pages int) IteratorOption {
+	return func(cfg *iteratorConfig) {
+		if pages >= 0 {
+			cfg.Prefetch = pages
+		}
+	}
+}
+
+// WithSeqBound stops iteration at the first item whose sequence is lower than seq.
+func WithSeqBound(seq int64) IteratorOption {
+	return func(cfg *iteratorConfig) {
+		cfg.MinSeq = seq
+	}
+}
+
+// WithTimeBound stops iteration at the first item older than t. It is
+// ignored by iterators over items that carry no time.
+func WithTimeBound(t time.Time) IteratorOption {
+	return func(cfg *iteratorConfig) {
+		cfg.Since = t
+	}
+}
+
+func newIteratorConfig(options []IteratorOption) *iteratorConfig {
+	cfg := &iteratorConfig{
+		PageSize: defaultIteratorPageSize,
+		Prefetch: defaultIteratorPrefetch,
+	}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// iteratorPage is one page of results handed from the fetching goroutine to Next.
+type iteratorPage[T any] struct {
+	items []T
+	err   error
+}
+
+// pageIterator walks a cursor based API, fetching pages in a background
+// goroutine. fetch is called repeatedly and keeps the cursor itself; it
+// reports last when no further page exists.
+type pageIterator[T any] struct {
+	fetch  func(ctx context.Context, pageSize int32) (items []T, last bool, err error)
+	seqOf  func(T) int64
+	timeOf func(T) int64
+	cfg    *iteratorConfig
+
+	pages  chan iteratorPage[T]
+	cancel context.CancelFunc
+
+	buf  []T
+	cur  T
+	err  error
+	last bool // set when fetching synchronously and the last page was fetched
+	done bool
+}
+
+func newPageIterator[T any](cfg *iteratorConfig, fetch func(context.Context, int32) ([]T, bool, error)) *pageIterator[T] {
+	return &pageIterator[T]{fetch: fetch, cfg: cfg}
+}
+
+// Next advances to the next item, fetching a new page if needed. It returns
+// false once iteration is finished, a bound is reached or an error occurs.
+// The first call starts background fetching bound to ctx, unless prefetching
+// is disabled.
+func (it *pageIterator[T]) Next(ctx context.Context) bool {
+	for {
+		if len(it.buf) > 0 {
+			it.cur, it.buf = it.buf[0], it.buf[1:]
+			if it.outOfBounds(it.cur) {
+				it.Close()
+				return false
+			}
+			return true
+		}
+		if it.done {
+			return false
+		}
+		if it.cfg.Prefetch == 0 {
+			if it.last {
+				it.done = true
+				return false
+			}
+			items, last, err := it.fetch(ctx, it.cfg.PageSize)
+			if err != nil {
+				it.err = err
+				it.Close()
+				return false
+			}
+			it.buf, it.last = items, last
+			continue
+		}
+		if it.pages == nil {
+			it.start(ctx)
+		}
+
+		select {
+		case page, ok := <-it.pages:
+			if !ok {
+				it.done = true
+				return false
+			}
+			if page.err != nil {
+				it.err = page.err
+				it.Close()
+				return false
+			}
+			it.buf = page.items
+		case <-ctx.Done():
+			it.err = ctx.Err()
+			it.Close()
+			return false
+		}
+	}
+}
+
+// Value returns the current item.
+func (it *pageIterator[T]) Value() T {
+	return it.cur
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *pageIterator[T]) Err() error {
+	return it.err
+}
+
+// Close stops background fetching. It is safe to call more than once and is
+// called automatically once iteration stops.
+func (it *pageIterator[T]) Close() {
+	it.done = true
+	it.buf = nil
+	if it.cancel != nil {
+		it.cancel()
+	}
+}
+
+func (it *pageIterator[T]) start(ctx context.Context) {
+	ctx, it.cancel = context.WithCancel(ctx)
+	it.pages = make(chan iteratorPage[T], it.cfg.Prefetch)
+
+	go func() {
+		defer close(it.pages)
+		for {
+			items, last, err := it.fetch(ctx, it.cfg.PageSize)
+			select {
+			case it.pages <- iteratorPage[T]{items: items, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil || last {
+				return
+			}
+		}
+	}()
+}
+
+func (it *pageIterator[T]) outOfBounds(item T) bool {
+	if it.cfg.MinSeq != 0 && it.seqOf != nil && it.seqOf(item) < it.cfg.MinSeq {
+		return true
+	}
+	if !it.cfg.Since.IsZero() && it.timeOf != nil && it.timeOf(item) < it.cfg.Since.Unix() {
+		return true
+	}
+	return false
+}
+
+// HistoryIterator iterates a conversation's messages from newest to oldest.
+type HistoryIterator struct {
+	*pageIterator[ReceiveMessage]
+}
+
+// NewHistoryIterator returns an iterator over GetHistoryMessages starting at
+// startMessageSeq and walking towards older messages.
+func NewHistoryIterator(s *Session, messageScene MessageScene, peerID int64, startMessageSeq int64, options ...IteratorOption) *HistoryIterator {
+	next := startMessageSeq
+	it := newPageIterator(newIteratorConfig(options), func(ctx context.Context, pageSize int32) ([]ReceiveMessage, bool, error) {
+		messages, nextMessageSeq, err := s.GetHistoryMessages(messageScene, peerID, next, pageSize, WithContext(ctx))
+		if err != nil {
+			return nil, true, err
+		}
+		next = nextMessageSeq
+		return messages, nextMessageSeq == 0 || len(messages) == 0, nil
+	})
+	it.seqOf = func(m ReceiveMessage) int64 { return m.MessageSeq }
+	it.timeOf = func(m ReceiveMessage) int64 { return m.Time }
+	return &HistoryIterator{it}
+}
+
+// EssenceIterator iterates a group's essence messages page by page.
+type EssenceIterator struct {
+	*pageIterator[GroupEssenceMessage]
+}
+
+// NewEssenceIterator returns an iterator over GetGroupEssenceMessages.
+func NewEssenceIterator(s *Session, groupID int64, options ...IteratorOption) *EssenceIterator {
+	var pageIndex int32
+	it := newPageIterator(newIteratorConfig(options), func(ctx context.Context, pageSize int32) ([]GroupEssenceMessage, bool, error) {
+		messages, isEnd, err := s.GetGroupEssenceMessages(groupID, pageIndex, pageSize, WithContext(ctx))
+		if err != nil {
+			return nil, true, err
+		}
+		pageIndex++
+		return messages, isEnd || len(messages) == 0, nil
+	})
+	it.seqOf = func(m GroupEssenceMessage) int64 { return m.MessageSeq }
+	it.timeOf = func(m GroupEssenceMessage) int64 { return m.OperationTime }
+	return &EssenceIterator{it}
+}
+
+// NotificationIterator iterates group notifications from newest to oldest.
+type NotificationIterator struct {
+	*pageIterator[GroupNotification]
+}
+
+// NewNotificationIterator returns an iterator over GetGroupNotifications
+// starting at startNotificationSeq.
+func NewNotificationIterator(s *Session, startNotificationSeq int64, isFiltered bool, options ...IteratorOption) *NotificationIterator {
+	next := startNotificationSeq
+	it := newPageIterator(newIteratorConfig(options), func(ctx context.Context, pageSize int32) ([]GroupNotification, bool, error) {
+		notifications, nextNotificationSeq, err := s.GetGroupNotifications(next, isFiltered, pageSize, WithContext(ctx))
+		if err != nil {
+			return nil, true, err
+		}
+		next = nextNotificationSeq
+		return notifications, nextNotificationSeq == 0 || len(notifications) == 0, nil
+	})
+	it.seqOf = func(n GroupNotification) int64 { return n.Base().NotificationSeq }
+	return &NotificationIterator{it}
+}
+
+// FriendRequestIterator iterates pending friend requests. GetFriendRequests
+// has no cursor, so the page size bounds the number of requests returned.
+type FriendRequestIterator struct {
+	*pageIterator[FriendRequest]
+}
+
+// NewFriendRequestIterator returns an iterator over GetFriendRequests.
+func NewFriendRequestIterator(s *Session, isFiltered bool, options ...IteratorOption) *FriendRequestIterator {
+	it := newPageIterator(newIteratorConfig(options), func(ctx context.Context, pageSize int32) ([]FriendRequest, bool, error) {
+		requests, err := s.GetFriendRequests(pageSize, isFiltered, WithContext(ctx))
+		return requests, true, err
+	})
+	return &FriendRequestIterator{it}
+}
@@ -0,0 +1,239 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newIteratorServer serves endpoint with the data returned by page for each
+// request body and counts the calls made to it.
+func newIteratorServer(t *testing.T, endpoint string, calls *int32, page func(body []byte) interface{}) *Session {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, endpoint) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		data, _ := json.Marshal(page(body))
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: data})
+	}))
+	t.Cleanup(server.Close)
+	s, _ := New("", server.URL, "", &TestLogger{})
+	return s
+}
+
+func TestHistoryIterator(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			StartMessageSeq int64 `json:"start_message_seq"`
+			Limit           int32 `json:"limit"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls++
+
+		// Serve messages 1..10 newest first, req.Limit at a time.
+		var messages []map[string]interface{}
+		seq := req.StartMessageSeq
+		for ; seq > 0 && int32(len(messages)) < req.Limit; seq-- {
+			messages = append(messages, map[string]interface{}{
+				"peer_id": 1, "message_seq": seq, "sender_id": 2, "time": 1000 + seq, "message_scene": "group",
+			})
+		}
+		data, _ := json.Marshal(map[string]interface{}{"messages": messages, "next_message_seq": seq})
+		_ = json.NewEncoder(w).Encode(APIResponse{Status: "ok", Data: data})
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+
+	it := NewHistoryIterator(s, "group", 1, 10, WithPageSize(3))
+	var seqs []int64
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(seqs) != 10 || seqs[0] != 10 || seqs[9] != 1 {
+		t.Fatalf("iterated %v, want 10..1", seqs)
+	}
+
+	calls = 0
+	it = NewHistoryIterator(s, "group", 1, 10, WithPageSize(3), WithSeqBound(6), WithPrefetch(0))
+	seqs = seqs[:0]
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if len(seqs) != 5 || seqs[4] != 6 {
+		t.Fatalf("iterated %v, want 10..6", seqs)
+	}
+	if calls != 2 {
+		t.Fatalf("expected iteration to stop fetching at the bound, made %d calls", calls)
+	}
+
+	calls = 0
+	it = NewHistoryIterator(s, "group", 1, 10, WithPageSize(3), WithTimeBound(time.Unix(1008, 0)))
+	seqs = seqs[:0]
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if len(seqs) != 3 || seqs[2] != 8 {
+		t.Fatalf("iterated %v, want 10..8", seqs)
+	}
+}
+
+func TestIteratorWithoutPrefetch(t *testing.T) {
+	var calls int32
+	s := newIteratorServer(t, EndpointGetHistoryMessages, &calls, func(body []byte) interface{} {
+		var req struct {
+			StartMessageSeq int64 `json:"start_message_seq"`
+		}
+		_ = json.Unmarshal(body, &req)
+		return map[string]interface{}{
+			"messages": []map[string]interface{}{{
+				"peer_id": 1, "message_seq": req.StartMessageSeq, "sender_id": 2, "time": 1000, "message_scene": "group",
+			}},
+			"next_message_seq": req.StartMessageSeq - 1,
+		}
+	})
+
+	it := NewHistoryIterator(s, "group", 1, 3, WithPageSize(1), WithPrefetch(0))
+	if !it.Next(context.Background()) || it.Value().MessageSeq != 3 {
+		t.Fatalf("first item: %+v, err %v", it.Value(), it.Err())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fetched %d pages ahead of Next", n)
+	}
+	var seqs []int64
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if len(seqs) != 2 || seqs[1] != 1 || it.Err() != nil {
+		t.Fatalf("iterated %v with err %v, want 2..1", seqs, it.Err())
+	}
+}
+
+func TestEssenceIterator(t *testing.T) {
+	var calls int32
+	s := newIteratorServer(t, EndpointGetGroupEssenceMessages, &calls, func(body []byte) interface{} {
+		var req struct {
+			PageIndex int32 `json:"page_index"`
+			PageSize  int32 `json:"page_size"`
+		}
+		_ = json.Unmarshal(body, &req)
+
+		// Serve essence messages 7..1, most recently set first.
+		var messages []map[string]interface{}
+		seq := 7 - int64(req.PageIndex*req.PageSize)
+		for ; seq > 0 && int32(len(messages)) < req.PageSize; seq-- {
+			messages = append(messages, map[string]interface{}{
+				"group_id": 1, "message_seq": seq, "message_time": 100 + seq, "sender_id": 2, "sender_name": "a",
+				"operator_id": 3, "operator_name": "b", "operation_time": 200 + seq, "segments": []interface{}{},
+			})
+		}
+		return map[string]interface{}{"messages": messages, "is_end": seq <= 0}
+	})
+
+	it := NewEssenceIterator(s, 1, WithPageSize(3))
+	var seqs []int64
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(seqs) != 7 || seqs[0] != 7 || seqs[6] != 1 || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("iterated %v in %d calls, want 7..1 in 3", seqs, calls)
+	}
+
+	it = NewEssenceIterator(s, 1, WithPageSize(3), WithTimeBound(time.Unix(204, 0)))
+	seqs = seqs[:0]
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().MessageSeq)
+	}
+	if len(seqs) != 4 || seqs[3] != 4 {
+		t.Fatalf("iterated %v, want 7..4", seqs)
+	}
+}
+
+func TestNotificationIterator(t *testing.T) {
+	var calls int32
+	s := newIteratorServer(t, EndpointGetGroupNotifications, &calls, func(body []byte) interface{} {
+		var req struct {
+			StartNotificationSeq int64 `json:"start_notification_seq"`
+			Limit                int32 `json:"limit"`
+		}
+		_ = json.Unmarshal(body, &req)
+
+		var notifications []map[string]interface{}
+		seq := req.StartNotificationSeq
+		for ; seq > 0 && int32(len(notifications)) < req.Limit; seq-- {
+			notifications = append(notifications, map[string]interface{}{
+				"type": "quit", "group_id": 1, "notification_seq": seq, "target_user_id": 2,
+			})
+		}
+		return map[string]interface{}{"notifications": notifications, "next_notification_seq": seq}
+	})
+
+	it := NewNotificationIterator(s, 6, false, WithPageSize(4))
+	var seqs []int64
+	for it.Next(context.Background()) {
+		if it.Value().NotificationType() != QuitType {
+			t.Fatalf("unexpected notification %+v", it.Value())
+		}
+		seqs = append(seqs, it.Value().Base().NotificationSeq)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(seqs) != 6 || seqs[0] != 6 || seqs[5] != 1 {
+		t.Fatalf("iterated %v, want 6..1", seqs)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	it = NewNotificationIterator(s, 6, false, WithPageSize(2), WithSeqBound(4), WithPrefetch(0))
+	seqs = seqs[:0]
+	for it.Next(context.Background()) {
+		seqs = append(seqs, it.Value().Base().NotificationSeq)
+	}
+	if len(seqs) != 3 || seqs[2] != 4 || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("iterated %v in %d calls, want 6..4 in 2", seqs, calls)
+	}
+}
+
+func TestFriendRequestIterator(t *testing.T) {
+	var calls int32
+	s := newIteratorServer(t, EndpointGetFriendRequests, &calls, func(body []byte) interface{} {
+		var req struct {
+			Limit int32 `json:"limit"`
+		}
+		_ = json.Unmarshal(body, &req)
+		var requests []map[string]interface{}
+		for i := int32(1); i <= req.Limit; i++ {
+			requests = append(requests, map[string]interface{}{"initiator_id": i})
+		}
+		return map[string]interface{}{"requests": requests}
+	})
+
+	it := NewFriendRequestIterator(s, false, WithPageSize(5))
+	var ids []int64
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().InitiatorID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if len(ids) != 5 || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("iterated %v in %d calls, want 5 requests in one call", ids, calls)
+	}
+}
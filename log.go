@@ -1,5 +1,29 @@
 package Milky_go_sdk
 
+import (
+	"fmt"
+	"strings"
+)
+
+// Logging levels for Session.LogLevel, a message is logged when its level is
+// less than or equal to LogLevel.
+const (
+	// LogError level is used for critical errors that could lead to data loss
+	// or panic that would not be returned to a calling function.
+	LogError int = iota
+
+	// LogWarning level is used for very abnormal events and errors that are
+	// also returned to a calling function.
+	LogWarning
+
+	// LogInformational level is used for normal non-error activity
+	LogInformational
+
+	// LogDebug level is for very detailed non-error activity.  This is
+	// very spammy and will impact performance.
+	LogDebug
+)
+
 type Logger interface {
 	Infof(format string, args ...interface{})
 	Errorf(format string, args ...interface{})
@@ -10,3 +34,136 @@ type Logger interface {
 	Debug(args ...interface{})
 	Warn(args ...interface{})
 }
+
+// FieldLogger is implemented by loggers that can attach structured fields to
+// log lines. keyvals alternate between string keys and values, as in log/slog.
+// Loggers that do not implement it get the fields appended to the message.
+type FieldLogger interface {
+	Logger
+	WithFields(keyvals ...interface{}) Logger
+}
+
+// NopLogger discards everything. It is used when a Session has no Logger.
+type NopLogger struct{}
+
+func (NopLogger) Infof(string, ...interface{})  {}
+func (NopLogger) Errorf(string, ...interface{}) {}
+func (NopLogger) Debugf(string, ...interface{}) {}
+func (NopLogger) Warnf(string, ...interface{})  {}
+func (NopLogger) Info(...interface{})           {}
+func (NopLogger) Error(...interface{})          {}
+func (NopLogger) Debug(...interface{})          {}
+func (NopLogger) Warn(...interface{})           {}
+
+// sessionLogger filters by Session.LogLevel, carries structured fields and
+// redacts secrets on top of the user supplied Logger.
+type sessionLogger struct {
+	base   Logger
+	level  int
+	fields []interface{}
+	redact func(string) string
+}
+
+// log returns the logger SDK code should use. It is never nil.
+func (s *Session) log() sessionLogger {
+	base := s.Logger
+	if base == nil {
+		base = NopLogger{}
+	}
+	return sessionLogger{base: base, level: s.LogLevel, redact: s.Redact}
+}
+
+// With returns a logger that attaches keyvals to every line.
+func (l sessionLogger) With(keyvals ...interface{}) sessionLogger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	l.fields = append(fields, keyvals...)
+	return l
+}
+
+// target returns the logger to write to and the suffix to append to the message.
+func (l sessionLogger) target() (Logger, string) {
+	if len(l.fields) == 0 {
+		return l.base, ""
+	}
+	if fl, ok := l.base.(FieldLogger); ok {
+		fields := make([]interface{}, len(l.fields))
+		for i, v := range l.fields {
+			if str, ok := v.(string); ok && i%2 == 1 {
+				v = l.redact(str)
+			}
+			fields[i] = v
+		}
+		return fl.WithFields(fields...), ""
+	}
+	var b strings.Builder
+	for i := 0; i < len(l.fields); i += 2 {
+		if i+1 < len(l.fields) {
+			fmt.Fprintf(&b, " %v=%v", l.fields[i], l.fields[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", l.fields[i])
+		}
+	}
+	return l.base, b.String()
+}
+
+// message formats a log line with its field suffix and redacts it.
+func (l sessionLogger) message(msg, suffix string) string {
+	return l.redact(msg + suffix)
+}
+
+func (l sessionLogger) Errorf(format string, args ...interface{}) {
+	if l.level >= LogError {
+		logger, suffix := l.target()
+		logger.Errorf("%s", l.message(fmt.Sprintf(format, args...), suffix))
+	}
+}
+
+func (l sessionLogger) Warnf(format string, args ...interface{}) {
+	if l.level >= LogWarning {
+		logger, suffix := l.target()
+		logger.Warnf("%s", l.message(fmt.Sprintf(format, args...), suffix))
+	}
+}
+
+func (l sessionLogger) Infof(format string, args ...interface{}) {
+	if l.level >= LogInformational {
+		logger, suffix := l.target()
+		logger.Infof("%s", l.message(fmt.Sprintf(format, args...), suffix))
+	}
+}
+
+func (l sessionLogger) Debugf(format string, args ...interface{}) {
+	if l.level >= LogDebug {
+		logger, suffix := l.target()
+		logger.Debugf("%s", l.message(fmt.Sprintf(format, args...), suffix))
+	}
+}
+
+func (l sessionLogger) Error(args ...interface{}) {
+	if l.level >= LogError {
+		logger, suffix := l.target()
+		logger.Error(l.message(fmt.Sprint(args...), suffix))
+	}
+}
+
+func (l sessionLogger) Warn(args ...interface{}) {
+	if l.level >= LogWarning {
+		logger, suffix := l.target()
+		logger.Warn(l.message(fmt.Sprint(args...), suffix))
+	}
+}
+
+func (l sessionLogger) Info(args ...interface{}) {
+	if l.level >= LogInformational {
+		logger, suffix := l.target()
+		logger.Info(l.message(fmt.Sprint(args...), suffix))
+	}
+}
+
+func (l sessionLogger) Debug(args ...interface{}) {
+	if l.level >= LogDebug {
+		logger, suffix := l.target()
+		logger.Debug(l.message(fmt.Sprint(args...), suffix))
+	}
+}
@@ -0,0 +1,138 @@
+package Milky_go_sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bufferLogger struct {
+	bytes.Buffer
+}
+
+func (l *bufferLogger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(l, "INFO "+format+"\n", args...)
+}
+func (l *bufferLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l, "ERROR "+format+"\n", args...)
+}
+func (l *bufferLogger) Debugf(format string, args ...interface{}) {
+	fmt.Fprintf(l, "DEBUG "+format+"\n", args...)
+}
+func (l *bufferLogger) Warnf(format string, args ...interface{}) {
+	fmt.Fprintf(l, "WARN "+format+"\n", args...)
+}
+func (l *bufferLogger) Info(args ...interface{})  { fmt.Fprintln(l, "INFO", fmt.Sprint(args...)) }
+func (l *bufferLogger) Error(args ...interface{}) { fmt.Fprintln(l, "ERROR", fmt.Sprint(args...)) }
+func (l *bufferLogger) Debug(args ...interface{}) { fmt.Fprintln(l, "DEBUG", fmt.Sprint(args...)) }
+func (l *bufferLogger) Warn(args ...interface{})  { fmt.Fprintln(l, "WARN", fmt.Sprint(args...)) }
+
+func TestLogLevelFiltering(t *testing.T) {
+	l := &bufferLogger{}
+	s := &Session{Logger: l, LogLevel: LogWarning}
+
+	s.log().Debugf("debug")
+	s.log().Infof("info")
+	s.log().Warnf("warn")
+	s.log().Errorf("error")
+
+	if got, want := l.String(), "WARN warn\nERROR error\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLogFieldsAppended(t *testing.T) {
+	l := &bufferLogger{}
+	s := &Session{Logger: l, LogLevel: LogDebug}
+
+	s.log().With("endpoint", "100%", "attempt", 1).Infof("retrying %s", "now")
+	s.log().With("group_id", 7).Warn("unknown")
+
+	want := "INFO retrying now endpoint=100% attempt=1\nWARN unknown group_id=7\n"
+	if got := l.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	s := &Session{LogLevel: LogDebug}
+	s.log().With("a", 1).Errorf("nothing %d", 1)
+	s.log().Debug("nothing")
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	s, _ := New("", srv.URL, "", NewSlogLogger(h))
+	s.LogLevel = LogLevelForSlog(slog.LevelInfo)
+	s.MaxRestRetries = 1
+	if _, err := s.GetLoginInfo(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one retry line, got %q", buf.String())
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["level"] != "INFO" || rec["endpoint"] != "get_login_info" || rec["attempt"] != float64(0) {
+		t.Errorf("unexpected record %v", rec)
+	}
+}
+
+func TestEventLogFields(t *testing.T) {
+	e := &Event{Type: "message_receive", RawData: json.RawMessage(`{"message_scene":"group","peer_id":42}`)}
+	fields := eventLogFields(e)
+	if len(fields) != 4 || fields[1] != "message_receive" || fields[3] != int64(42) {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	e = &Event{Type: "friend_request", RawData: json.RawMessage(`{"initiator_id":1}`)}
+	if fields = eventLogFields(e); len(fields) != 2 {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	l := &bufferLogger{}
+	s := &Session{Logger: l, LogLevel: LogDebug, Token: "s3cret", SensitiveFields: []string{"password"}}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{"cookies":"uin=1; skey=abc","csrf_token":"12345"}}`))
+	}))
+	defer srv.Close()
+	s.Client = srv.Client()
+	s.apiEndpoints = newAPIEndpoints(srv.URL)
+
+	if _, err := s.GetCookies("qun.qq.com"); err != nil {
+		t.Fatal(err)
+	}
+	s.log().Infof("dialing ws://host/event?access_token=%s&x=1", "other")
+	s.log().Infof(`payload {"password": "hunter2", "user": "bob"}`)
+
+	out := l.String()
+	for _, secret := range []string{"s3cret", "skey=abc", "12345", "other", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains %q:\n%s", secret, out)
+		}
+	}
+	for _, kept := range []string{"[Authorization] = [REDACTED]", `"cookies":"[REDACTED]"`, "access_token=[REDACTED]&x=1", `"user": "bob"`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("log output is missing %q:\n%s", kept, out)
+		}
+	}
+}
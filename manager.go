@@ -0,0 +1,250 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// All manager error constants
+var (
+	ErrAccountExists   = errors.New("an account with this self ID is already managed")
+	ErrAccountNotFound = errors.New("no managed account with this self ID")
+)
+
+// AccountConfig describes one account to run in a Manager.
+type AccountConfig struct {
+	WSGateway   string
+	RestGateway string
+	Token       string
+
+	// Called with the new session before it connects, to set options such
+	// as SyncEvents, WorkerPool or Metrics.
+	Configure func(s *Session)
+}
+
+// AccountEvent is an event received by one of the accounts of a Manager.
+type AccountEvent struct {
+	SelfID  int64
+	Session *Session
+	Type    string      // event type, such as "message_receive"
+	Event   interface{} // the event struct, such as *ReceiveMessage
+}
+
+// AccountStatus is a snapshot of a managed account.
+type AccountStatus struct {
+	SelfID           int64
+	Nickname         string
+	Connected        bool
+	StartedAt        time.Time
+	LastHeartbeatAck time.Time
+}
+
+// Manager runs several accounts, each in its own Session, and routes their
+// events to shared handlers with the source account attached. Accounts can be
+// started and stopped at any time.
+type Manager struct {
+	// Logger used for sessions created by Start.
+	Logger Logger
+
+	mu       sync.RWMutex
+	accounts map[int64]*managedAccount
+
+	handlersMu sync.RWMutex
+	handlers   map[int]func(*AccountEvent)
+	nextID     int
+}
+
+type managedAccount struct {
+	session   *Session
+	nickname  string
+	startedAt time.Time
+	remove    func()
+}
+
+// NewManager returns a Manager without accounts.
+func NewManager(logger Logger) *Manager {
+	return &Manager{
+		Logger:   logger,
+		accounts: map[int64]*managedAccount{},
+		handlers: map[int]func(*AccountEvent){},
+	}
+}
+
+// Start creates a session for cfg, connects it and adds it to the manager.
+// It returns the account's self ID.
+func (m *Manager) Start(cfg AccountConfig) (int64, error) {
+	s, err := New(cfg.WSGateway, cfg.RestGateway, cfg.Token, m.Logger)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.Configure != nil {
+		cfg.Configure(s)
+	}
+	return m.AddSession(s)
+}
+
+// AddSession adds a session that is not yet open to the manager: it looks up
+// the account's self ID, routes the session's events to the manager's
+// handlers and opens the websocket. The manager closes the session when the
+// account is stopped.
+func (m *Manager) AddSession(s *Session) (int64, error) {
+	info, err := s.GetLoginInfo()
+	if err != nil {
+		return 0, fmt.Errorf("getting login info: %w", err)
+	}
+
+	account := &managedAccount{session: s, nickname: info.Nickname, startedAt: time.Now()}
+	m.mu.Lock()
+	if _, ok := m.accounts[info.UIN]; ok {
+		m.mu.Unlock()
+		return 0, ErrAccountExists
+	}
+	m.accounts[info.UIN] = account
+	m.mu.Unlock()
+
+	selfID := info.UIN
+	account.remove = s.AddHandler(func(s *Session, i interface{}) {
+		m.dispatch(&AccountEvent{SelfID: selfID, Session: s, Type: eventTypeFor(i), Event: i})
+	})
+
+	if err = s.Open(); err != nil {
+		account.remove()
+		m.mu.Lock()
+		delete(m.accounts, selfID)
+		m.mu.Unlock()
+		return 0, err
+	}
+	return selfID, nil
+}
+
+// Stop closes the account's session and removes it from the manager.
+func (m *Manager) Stop(selfID int64) error {
+	m.mu.Lock()
+	account, ok := m.accounts[selfID]
+	delete(m.accounts, selfID)
+	m.mu.Unlock()
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	account.remove()
+	return account.session.Close()
+}
+
+// Close stops all accounts and returns the first error.
+func (m *Manager) Close() error {
+	var firstErr error
+	for _, selfID := range m.SelfIDs() {
+		if err := m.Stop(selfID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Session returns the session of an account, or nil if it is not managed.
+func (m *Manager) Session(selfID int64) *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if account, ok := m.accounts[selfID]; ok {
+		return account.session
+	}
+	return nil
+}
+
+// SelfIDs returns the self IDs of all managed accounts in ascending order.
+func (m *Manager) SelfIDs() []int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]int64, 0, len(m.accounts))
+	for id := range m.accounts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Status returns a snapshot of every managed account, ordered by self ID.
+func (m *Manager) Status() []AccountStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := make([]AccountStatus, 0, len(m.accounts))
+	for id, account := range m.accounts {
+		s := account.session
+		s.RLock()
+		status = append(status, AccountStatus{
+			SelfID:           id,
+			Nickname:         account.nickname,
+			Connected:        s.wsConn != nil,
+			StartedAt:        account.startedAt,
+			LastHeartbeatAck: s.LastHeartbeatAck,
+		})
+		s.RUnlock()
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].SelfID < status[j].SelfID })
+	return status
+}
+
+// Healthy reports whether every managed account is connected.
+func (m *Manager) Healthy() bool {
+	for _, status := range m.Status() {
+		if !status.Connected {
+			return false
+		}
+	}
+	return true
+}
+
+// AddHandler registers a handler called with the events of every account.
+// Handlers run as configured on the session that received the event. The
+// returned function removes the handler.
+func (m *Manager) AddHandler(handler func(e *AccountEvent)) func() {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	id := m.nextID
+	m.nextID++
+	m.handlers[id] = handler
+	return func() {
+		m.handlersMu.Lock()
+		defer m.handlersMu.Unlock()
+		delete(m.handlers, id)
+	}
+}
+
+func (m *Manager) dispatch(e *AccountEvent) {
+	m.handlersMu.RLock()
+	ids := make([]int, 0, len(m.handlers))
+	for id := range m.handlers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	handlers := make([]func(*AccountEvent), 0, len(ids))
+	for _, id := range ids {
+		handlers = append(handlers, m.handlers[id])
+	}
+	m.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+var (
+	eventTypesOnce sync.Once
+	eventTypes     map[reflect.Type]string
+)
+
+// eventTypeFor returns the event type of an event struct.
+func eventTypeFor(i interface{}) string {
+	eventTypesOnce.Do(func() {
+		eventTypes = map[reflect.Type]string{}
+		for t, p := range eventProviders() {
+			eventTypes[reflect.TypeOf(p.New())] = t
+		}
+	})
+	return eventTypes[reflect.TypeOf(i)]
+}
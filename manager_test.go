@@ -0,0 +1,74 @@
+package Milky_go_sdk_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	milky "github.com/Szzrain/Milky-go-sdk"
+	"github.com/Szzrain/Milky-go-sdk/milkytest"
+)
+
+func startAccount(t *testing.T, m *milky.Manager, uin int64) *milkytest.Server {
+	t.Helper()
+	server := milkytest.NewServer(t)
+	server.Respond(milky.EndpointGetLoginInfo, milky.LoginInfo{UIN: uin, Nickname: "bot"})
+	selfID, err := m.Start(milky.AccountConfig{WSGateway: server.WSURL, RestGateway: server.RestURL})
+	if err != nil {
+		t.Fatalf("starting %d: %v", uin, err)
+	}
+	if selfID != uin {
+		t.Fatalf("started %d, want %d", selfID, uin)
+	}
+	if err = server.WaitForConnections(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestManager(t *testing.T) {
+	m := milky.NewManager(milkytest.NewLogger(t))
+	defer m.Close()
+
+	events := make(chan *milky.AccountEvent, 4)
+	m.AddHandler(func(e *milky.AccountEvent) {
+		if _, ready := e.Event.(*milky.Ready); !ready {
+			events <- e
+		}
+	})
+
+	first := startAccount(t, m, 1001)
+	second := startAccount(t, m, 1002)
+
+	if _, err := m.Start(milky.AccountConfig{WSGateway: first.WSURL, RestGateway: first.RestURL}); !errors.Is(err, milky.ErrAccountExists) {
+		t.Errorf("starting a duplicate account returned %v", err)
+	}
+
+	if err := second.Push("group_nudge", milky.GroupNudge{GroupID: 5}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-events:
+		nudge, ok := e.Event.(*milky.GroupNudge)
+		if e.SelfID != 1002 || e.Type != "group_nudge" || e.Session != m.Session(1002) || !ok || nudge.GroupID != 5 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not routed")
+	}
+
+	status := m.Status()
+	if len(status) != 2 || status[0].SelfID != 1001 || !status[0].Connected || !m.Healthy() {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if err := m.Stop(1001); err != nil {
+		t.Fatal(err)
+	}
+	if m.Session(1001) != nil || len(m.SelfIDs()) != 1 {
+		t.Errorf("account 1001 still managed: %v", m.SelfIDs())
+	}
+	if err := m.Stop(1001); !errors.Is(err, milky.ErrAccountNotFound) {
+		t.Errorf("stopping a stopped account returned %v", err)
+	}
+}
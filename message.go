@@ -1,6 +1,9 @@
 package Milky_go_sdk
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 type (
 	RawMessageElement struct {
@@ -129,8 +132,8 @@ func (t *ReplyElement) MarshalJSON() ([]byte, error) {
 
 type ImageElement struct {
 	// I & O
-	Summary string `json:"summary"`  // 图片摘要
-	SubType string `json:"sub_type"` // 图片子类型
+	Summary string       `json:"summary"`  // 图片摘要
+	SubType ImageSubType `json:"sub_type"` // 图片子类型，发送时为空则由实现决定
 	// O
 	URI string `json:"uri,omitempty"` // 图片URI
 	// I
@@ -145,19 +148,22 @@ func (l *ImageElement) Type() MessageElementType {
 }
 
 func (l *ImageElement) MarshalJSON() ([]byte, error) {
+	if l.SubType != "" && !l.SubType.IsValid() {
+		return nil, fmt.Errorf("%w: image sub type %q", ErrInvalidEnum, l.SubType)
+	}
 	return json.Marshal(&struct {
 		Type MessageElementType `json:"type"`
 		Data struct {
-			URI     string `json:"uri"`
-			Summary string `json:"summary"`
-			SubType string `json:"sub_type"`
+			URI     string       `json:"uri"`
+			Summary string       `json:"summary"`
+			SubType ImageSubType `json:"sub_type"`
 		} `json:"data"`
 	}{
 		Type: l.Type(),
 		Data: struct {
-			URI     string `json:"uri"`
-			Summary string `json:"summary"`
-			SubType string `json:"sub_type"`
+			URI     string       `json:"uri"`
+			Summary string       `json:"summary"`
+			SubType ImageSubType `json:"sub_type"`
 		}{
 			URI:     l.URI,
 			Summary: l.Summary,
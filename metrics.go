@@ -0,0 +1,250 @@
+package Milky_go_sdk
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestMetric describes one REST call, including any retries.
+type RequestMetric struct {
+	Endpoint string
+	Status   int // HTTP status of the last attempt, 0 if no response was received
+	RetCode  int // retcode of the API response, only set for HTTP 200
+	Latency  time.Duration
+	Retries  int
+	Err      error // transport or HTTP error, API failures are reported in RetCode
+}
+
+// Metrics receives measurements from a Session. Implementations must be safe
+// for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called once per REST call.
+	ObserveRequest(m RequestMetric)
+
+	// ObserveEvent is called for every event received, with the time taken
+	// to decode it.
+	ObserveEvent(eventType string, decode time.Duration)
+
+	// ObserveHandler is called after each event handler returns.
+	ObserveHandler(eventType string, d time.Duration)
+
+	// ObserveReconnect is called after each reconnect attempt, err is nil
+	// when the attempt succeeded.
+	ObserveReconnect(err error)
+
+	// SetQueueDepth reports the number of event handlers waiting or running.
+	SetQueueDepth(depth int)
+}
+
+// NopMetrics discards all measurements. It is the default.
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveRequest(RequestMetric)         {}
+func (NopMetrics) ObserveEvent(string, time.Duration)   {}
+func (NopMetrics) ObserveHandler(string, time.Duration) {}
+func (NopMetrics) ObserveReconnect(error)               {}
+func (NopMetrics) SetQueueDepth(int)                    {}
+
+// metrics returns the Metrics to report to. It is never nil.
+func (s *Session) metrics() Metrics {
+	if s.Metrics == nil {
+		return NopMetrics{}
+	}
+	return s.Metrics
+}
+
+// DefaultLatencyBuckets are the histogram buckets, in seconds, used by
+// NewPrometheusMetrics.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics collects Session measurements and serves them in the
+// Prometheus text exposition format. It has no dependencies on the
+// Prometheus client libraries.
+type PrometheusMetrics struct {
+	// Prefix of every metric name.
+	Namespace string
+
+	buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+	queueDepth int
+}
+
+// NewPrometheusMetrics returns an empty collector. Metric names are prefixed
+// with namespace, "milky" when empty.
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	if namespace == "" {
+		namespace = "milky"
+	}
+	return &PrometheusMetrics{
+		Namespace:  namespace,
+		buckets:    DefaultLatencyBuckets,
+		counters:   map[string]map[string]float64{},
+		histograms: map[string]map[string]*histogram{},
+	}
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+var metricHelp = map[string]string{
+	"rest_requests_total":           "REST calls by endpoint, HTTP status and retcode.",
+	"rest_request_errors_total":     "REST calls that failed with a transport, HTTP or API error.",
+	"rest_retries_total":            "REST attempts retried after a bad gateway response.",
+	"rest_request_duration_seconds": "REST call latency including retries.",
+	"events_total":                  "Events received by type.",
+	"event_decode_duration_seconds": "Time taken to decode an event.",
+	"handler_duration_seconds":      "Time taken by event handlers.",
+	"reconnects_total":              "Websocket reconnect attempts by result.",
+	"handler_queue_depth":           "Event handlers waiting or running.",
+}
+
+func (p *PrometheusMetrics) add(name, labels string, v float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counters[name] == nil {
+		p.counters[name] = map[string]float64{}
+	}
+	p.counters[name][labels] += v
+}
+
+func (p *PrometheusMetrics) observe(name, labels string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.histograms[name] == nil {
+		p.histograms[name] = map[string]*histogram{}
+	}
+	h := p.histograms[name][labels]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.histograms[name][labels] = h
+	}
+	v := d.Seconds()
+	for i, le := range p.buckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// ObserveRequest implements Metrics.
+func (p *PrometheusMetrics) ObserveRequest(m RequestMetric) {
+	endpoint := labelPair("endpoint", m.Endpoint)
+	p.add("rest_requests_total", endpoint+","+labelPair("status", strconv.Itoa(m.Status))+","+labelPair("retcode", strconv.Itoa(m.RetCode)), 1)
+	if m.Err != nil || m.RetCode != 0 {
+		p.add("rest_request_errors_total", endpoint, 1)
+	}
+	if m.Retries > 0 {
+		p.add("rest_retries_total", endpoint, float64(m.Retries))
+	}
+	p.observe("rest_request_duration_seconds", endpoint, m.Latency)
+}
+
+// ObserveEvent implements Metrics.
+func (p *PrometheusMetrics) ObserveEvent(eventType string, decode time.Duration) {
+	labels := labelPair("event_type", eventType)
+	p.add("events_total", labels, 1)
+	p.observe("event_decode_duration_seconds", labels, decode)
+}
+
+// ObserveHandler implements Metrics.
+func (p *PrometheusMetrics) ObserveHandler(eventType string, d time.Duration) {
+	p.observe("handler_duration_seconds", labelPair("event_type", eventType), d)
+}
+
+// ObserveReconnect implements Metrics.
+func (p *PrometheusMetrics) ObserveReconnect(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	p.add("reconnects_total", labelPair("result", result), 1)
+}
+
+// SetQueueDepth implements Metrics.
+func (p *PrometheusMetrics) SetQueueDepth(depth int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queueDepth = depth
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.WriteText(w)
+}
+
+// WriteText writes all metrics in the Prometheus text format to w.
+func (p *PrometheusMetrics) WriteText(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(p.counters) {
+		p.header(&b, name, "counter")
+		series := p.counters[name]
+		for _, labels := range sortedKeys(series) {
+			fmt.Fprintf(&b, "%s_%s{%s} %s\n", p.Namespace, name, labels, formatFloat(series[labels]))
+		}
+	}
+	for _, name := range sortedKeys(p.histograms) {
+		p.header(&b, name, "histogram")
+		series := p.histograms[name]
+		for _, labels := range sortedKeys(series) {
+			h := series[labels]
+			var cumulative uint64
+			for i, le := range p.buckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(&b, "%s_%s_bucket{%s,le=\"%s\"} %d\n", p.Namespace, name, labels, formatFloat(le), cumulative)
+			}
+			fmt.Fprintf(&b, "%s_%s_bucket{%s,le=\"+Inf\"} %d\n", p.Namespace, name, labels, h.count)
+			fmt.Fprintf(&b, "%s_%s_sum{%s} %s\n", p.Namespace, name, labels, formatFloat(h.sum))
+			fmt.Fprintf(&b, "%s_%s_count{%s} %d\n", p.Namespace, name, labels, h.count)
+		}
+	}
+	p.header(&b, "handler_queue_depth", "gauge")
+	fmt.Fprintf(&b, "%s_handler_queue_depth %d\n", p.Namespace, p.queueDepth)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *PrometheusMetrics) header(b *strings.Builder, name, kind string) {
+	if help := metricHelp[name]; help != "" {
+		fmt.Fprintf(b, "# HELP %s_%s %s\n", p.Namespace, name, help)
+	}
+	fmt.Fprintf(b, "# TYPE %s_%s %s\n", p.Namespace, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelPair(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,68 @@
+package Milky_go_sdk
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"failed","retcode":-404,"message":"not found"}`))
+	}))
+	defer srv.Close()
+
+	metrics := NewPrometheusMetrics("")
+	s, _ := New("", srv.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.Metrics = metrics
+	s.SyncEvents = true
+
+	if _, err := s.GetLoginInfo(); err == nil {
+		t.Fatal("expected an API error")
+	}
+
+	handled := 0
+	s.AddHandler(func(s *Session, n *GroupNudge) { handled++ })
+	if _, err := s.dispatchFrame([]byte(`{"event_type":"group_nudge","data":{"group_id":1,"sender_id":2,"receiver_id":3}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 1 {
+		t.Fatalf("handler called %d times", handled)
+	}
+	metrics.ObserveReconnect(nil)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`milky_rest_requests_total{endpoint="get_login_info",status="200",retcode="-404"} 1`,
+		`milky_rest_request_errors_total{endpoint="get_login_info"} 1`,
+		`milky_rest_retries_total{endpoint="get_login_info"} 1`,
+		`milky_rest_request_duration_seconds_count{endpoint="get_login_info"} 1`,
+		`milky_events_total{event_type="group_nudge"} 1`,
+		`milky_handler_duration_seconds_bucket{event_type="group_nudge",le="+Inf"} 1`,
+		`milky_reconnects_total{result="success"} 1`,
+		`# TYPE milky_handler_queue_depth gauge`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	if got, want := labelPair("endpoint", "a\"b\\c\nd"), `endpoint="a\"b\\c\nd"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
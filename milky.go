@@ -23,6 +23,8 @@ func New(wsGateway string, restGateway string, token string, logger Logger) (s *
 		WSGateway:              wsGateway,
 		RestGateway:            restGateway,
 		Logger:                 logger,
+		LogLevel:               LogDebug,
+		Metrics:                NopMetrics{},
 		Token:                  token,
 		apiEndpoints:           newAPIEndpoints(restGateway),
 	}
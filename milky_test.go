@@ -50,6 +50,9 @@ func (l *TestLogger) Warn(args ...interface{}) {
 }
 
 func TestMilky(m *testing.T) {
+	if os.Getenv("TEST_WS_GATEWAY") == "" {
+		m.Skip("TEST_WS_GATEWAY not set, skipping live gateway test; see the milkytest package for offline tests")
+	}
 	logger := &TestLogger{}
 	fmt.Println("Gateway WS:", os.Getenv("TEST_WS_GATEWAY"))
 	session, err := New(os.Getenv("TEST_WS_GATEWAY"), os.Getenv("TEST_REST_GATEWAY"), os.Getenv("TEST_ACCESS_TOKEN"), logger)
@@ -60,9 +63,9 @@ func TestMilky(m *testing.T) {
 		if m == nil {
 			return
 		}
-		if m.MessageScene == "group" {
+		if m.MessageScene == SceneGroup {
 			fmt.Printf("Received group message: GroupId %d, MessageSeq %d, ", m.Group.GroupId, m.MessageSeq)
-		} else if m.MessageScene == "friend" {
+		} else if m.MessageScene == SceneFriend {
 			fmt.Printf("Received friend message: SenderName %s, MessageSeq %d, FriendCategoryID %d, FriendCategoryName %s ", m.Friend.Nickname, m.MessageSeq, m.Friend.Category.CategoryID, m.Friend.Category.CategoryName)
 		}
 		fmt.Printf("Received message: Sender %d", m.SenderId)
@@ -133,7 +136,7 @@ func TestMilky(m *testing.T) {
 	image := ImageElement{
 		URI:     "https://i2.hdslb.com/bfs/archive/7fac120d07a58a936bd877ceeb53f1e6388ee6e7.jpg",
 		Summary: "image.jpg",
-		SubType: "normal",
+		SubType: ImageNormal,
 	}
 	var elements []IMessageElement
 	elements = append(elements, &text)
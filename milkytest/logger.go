@@ -0,0 +1,71 @@
+package milkytest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	milky "github.com/Szzrain/Milky-go-sdk"
+)
+
+// testLogger writes SDK log output to the test log, so it is only shown for
+// failing tests or with -v. Output from goroutines that outlive the test is
+// dropped, as testing panics on logging after a test has completed.
+type testLogger struct {
+	tb   testing.TB
+	mu   sync.RWMutex
+	done bool
+}
+
+// NewLogger returns a Logger that writes to tb.
+func NewLogger(tb testing.TB) milky.Logger {
+	l := &testLogger{tb: tb}
+	tb.Cleanup(func() {
+		l.mu.Lock()
+		l.done = true
+		l.mu.Unlock()
+	})
+	return l
+}
+
+func (l *testLogger) log(level string, msg string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.done {
+		return
+	}
+	l.tb.Helper()
+	l.tb.Log(level + ": " + msg)
+}
+
+func (l *testLogger) Infof(format string, args ...interface{}) {
+	l.log("INFO", fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.log("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	l.log("DEBUG", fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Warnf(format string, args ...interface{}) {
+	l.log("WARN", fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Info(args ...interface{}) {
+	l.log("INFO", fmt.Sprint(args...))
+}
+
+func (l *testLogger) Error(args ...interface{}) {
+	l.log("ERROR", fmt.Sprint(args...))
+}
+
+func (l *testLogger) Debug(args ...interface{}) {
+	l.log("DEBUG", fmt.Sprint(args...))
+}
+
+func (l *testLogger) Warn(args ...interface{}) {
+	l.log("WARN", fmt.Sprint(args...))
+}
@@ -0,0 +1,393 @@
+// Package milkytest provides an in-process fake Milky implementation for
+// testing bots built on Milky_go_sdk without a live gateway.
+//
+// A Server speaks the Milky REST API under /api and pushes events over the
+// WebSocket at /event. API responses can be stubbed per endpoint, every call
+// is recorded, and failures such as error statuses, slow responses and
+// dropped connections can be injected.
+package milkytest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	milky "github.com/Szzrain/Milky-go-sdk"
+)
+
+// ErrTimeout is returned by the Wait methods when the deadline passes.
+var ErrTimeout = errors.New("milkytest: timed out waiting")
+
+// HandlerFunc produces the data of an API response from the request payload.
+// Returning an *APIError makes the call fail with that retcode.
+type HandlerFunc func(payload json.RawMessage) (interface{}, error)
+
+// APIError makes a stubbed endpoint answer with a failed API response.
+type APIError struct {
+	RetCode int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("retcode %d: %s", e.RetCode, e.Message)
+}
+
+// Call is a recorded API call.
+type Call struct {
+	Endpoint string
+	Header   http.Header
+	Payload  json.RawMessage
+	Time     time.Time
+}
+
+// Decode unmarshals the call payload into v.
+func (c Call) Decode(v interface{}) error {
+	return json.Unmarshal(c.Payload, v)
+}
+
+// fault is a failure injected into the next calls of an endpoint.
+type fault struct {
+	status    int
+	remaining int
+}
+
+// Server is a fake Milky implementation.
+type Server struct {
+	*httptest.Server
+
+	// Token, when set, must be presented by clients like a real implementation requires.
+	Token string
+
+	// WSURL and RestURL are the gateways to hand to milky.New.
+	WSURL   string
+	RestURL string
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	calls    []Call
+	faults   map[string]*fault
+	delays   map[string]time.Duration
+	conns    map[*websocket.Conn]*sync.Mutex
+	changed  chan struct{}
+	upgrader websocket.Upgrader
+}
+
+// NewServer starts a Server. It is closed automatically when the test ends.
+// get_impl_info is stubbed to report the SDK's Milky version, so the
+// handshake done by Session.Open succeeds.
+func NewServer(tb testing.TB) *Server {
+	s := &Server{
+		handlers: map[string]HandlerFunc{},
+		faults:   map[string]*fault{},
+		delays:   map[string]time.Duration{},
+		conns:    map[*websocket.Conn]*sync.Mutex{},
+		changed:  make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/", s.serveAPI)
+	mux.HandleFunc("/event", s.serveEvents)
+	s.Server = httptest.NewServer(mux)
+	s.RestURL = s.Server.URL + "/api"
+	s.WSURL = "ws" + strings.TrimPrefix(s.Server.URL, "http") + "/event"
+	s.Respond(milky.EndpointGetImplInfo, milky.ImplInfo{ImplName: "milkytest", MilkyVersion: milky.MilkyVersion})
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// NewSession returns a Session pointed at the server that logs through tb.
+// The session is closed when the test ends.
+func (s *Server) NewSession(tb testing.TB) *milky.Session {
+	session, err := milky.New(s.WSURL, s.RestURL, s.Token, NewLogger(tb))
+	if err != nil {
+		tb.Fatalf("milkytest: creating session: %v", err)
+	}
+	tb.Cleanup(func() {
+		_ = session.Close()
+	})
+	return session
+}
+
+// Close disconnects all clients and shuts the server down.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.Server.Close()
+}
+
+// Handle stubs endpoint with fn, replacing any earlier stub.
+func (s *Server) Handle(endpoint string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[endpoint] = fn
+}
+
+// Respond stubs endpoint to always answer with data.
+func (s *Server) Respond(endpoint string, data interface{}) {
+	s.Handle(endpoint, func(json.RawMessage) (interface{}, error) {
+		return data, nil
+	})
+}
+
+// RespondError stubs endpoint to always fail with retcode and message.
+func (s *Server) RespondError(endpoint string, retcode int, message string) {
+	s.Handle(endpoint, func(json.RawMessage) (interface{}, error) {
+		return nil, &APIError{RetCode: retcode, Message: message}
+	})
+}
+
+// FailNext makes the next times calls to endpoint answer with the HTTP
+// status, e.g. http.StatusBadGateway. An empty endpoint matches every call.
+func (s *Server) FailNext(endpoint string, status int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[endpoint] = &fault{status: status, remaining: times}
+}
+
+// Delay makes calls to endpoint wait d before answering. An empty endpoint
+// matches every call; a zero duration removes the delay.
+func (s *Server) Delay(endpoint string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d <= 0 {
+		delete(s.delays, endpoint)
+		return
+	}
+	s.delays[endpoint] = d
+}
+
+// Calls returns the recorded calls to endpoint, or all calls if endpoint is empty.
+func (s *Server) Calls(endpoint string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, c := range s.calls {
+		if endpoint == "" || c.Endpoint == endpoint {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets all recorded calls.
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// WaitForCall waits until endpoint has been called at least n times and
+// returns the nth call.
+func (s *Server) WaitForCall(endpoint string, n int, timeout time.Duration) (Call, error) {
+	var call Call
+	err := s.waitFor(timeout, func() bool {
+		calls := s.calls[:0:0]
+		for _, c := range s.calls {
+			if c.Endpoint == endpoint {
+				calls = append(calls, c)
+			}
+		}
+		if len(calls) >= n {
+			call = calls[n-1]
+			return true
+		}
+		return false
+	})
+	return call, err
+}
+
+// WaitForConnections waits until at least n event clients are connected.
+func (s *Server) WaitForConnections(n int, timeout time.Duration) error {
+	return s.waitFor(timeout, func() bool {
+		return len(s.conns) >= n
+	})
+}
+
+// Connections returns the number of connected event clients.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Push sends an event of eventType carrying data to every connected client.
+func (s *Server) Push(eventType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	frame, err := json.Marshal(map[string]interface{}{
+		"time":       time.Now().Unix(),
+		"self_id":    0,
+		"event_type": eventType,
+		"data":       json.RawMessage(raw),
+	})
+	if err != nil {
+		return err
+	}
+	return s.PushRaw(frame)
+}
+
+// PushRaw sends frame verbatim to every connected client.
+func (s *Server) PushRaw(frame []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.conns) == 0 {
+		return errors.New("milkytest: no connected event clients")
+	}
+	var firstErr error
+	for conn, writeMu := range s.conns {
+		writeMu.Lock()
+		err := conn.WriteMessage(websocket.TextMessage, frame)
+		writeMu.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Disconnect drops every event connection without a close handshake, like
+// a crashing implementation would.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+		delete(s.conns, conn)
+	}
+	s.notify()
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/api/")
+	payload, _ := io.ReadAll(r.Body)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Endpoint: endpoint, Header: r.Header.Clone(), Payload: payload, Time: time.Now()})
+	s.notify()
+	handler := s.handlers[endpoint]
+	delay, ok := s.delays[endpoint]
+	if !ok {
+		delay = s.delays[""]
+	}
+	status := s.takeFault(endpoint)
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if !s.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	var data interface{} = struct{}{}
+	var err error
+	if handler != nil {
+		data, err = handler(payload)
+	}
+
+	response := map[string]interface{}{"status": "ok", "retcode": 0, "data": data}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		response = map[string]interface{}{"status": "failed", "retcode": apiErr.RetCode, "message": apiErr.Message}
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[conn] = &sync.Mutex{}
+	s.notify()
+	s.mu.Unlock()
+
+	// Drain client frames such as heartbeats until the connection goes away.
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	if _, ok := s.conns[conn]; ok {
+		_ = conn.Close()
+		delete(s.conns, conn)
+		s.notify()
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+	return r.Header.Get("Authorization") == "Bearer "+s.Token || r.URL.Query().Get("access_token") == s.Token
+}
+
+// takeFault consumes one injected failure for endpoint. s.mu must be held.
+func (s *Server) takeFault(endpoint string) int {
+	for _, key := range []string{endpoint, ""} {
+		if f, ok := s.faults[key]; ok && f.remaining > 0 {
+			f.remaining--
+			return f.status
+		}
+	}
+	return 0
+}
+
+// notify wakes up waiters after a state change. s.mu must be held.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// waitFor polls cond under s.mu each time the server state changes.
+func (s *Server) waitFor(timeout time.Duration, cond func() bool) error {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		ok := cond()
+		changed := s.changed
+		s.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return ErrTimeout
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package milkytest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	milky "github.com/Szzrain/Milky-go-sdk"
+	"github.com/Szzrain/Milky-go-sdk/milkytest"
+)
+
+func TestServerEventsAndCalls(t *testing.T) {
+	server := milkytest.NewServer(t)
+	server.Token = "secret"
+	server.Respond(milky.EndpointSendGroupMessage, milky.MessageRet{MessageSeq: 42, Time: 1700000000})
+
+	session := server.NewSession(t)
+	received := make(chan *milky.ReceiveMessage, 1)
+	session.AddHandler(func(s *milky.Session, m *milky.ReceiveMessage) {
+		received <- m
+	})
+	if err := session.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := server.WaitForConnections(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	err := server.Push("message_receive", map[string]interface{}{
+		"peer_id": 100, "message_seq": 1, "sender_id": 7, "time": 1700000000, "message_scene": "group",
+		"segments": []map[string]interface{}{{"type": "text", "data": map[string]string{"text": "ping"}}},
+	})
+	if err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	var m *milky.ReceiveMessage
+	select {
+	case m = <-received:
+	case <-time.After(time.Second):
+		t.Fatal("message was not dispatched")
+	}
+	if text, ok := m.Segments[0].(*milky.TextElement); !ok || text.Text != "ping" {
+		t.Fatalf("unexpected segments %v", m.Segments)
+	}
+
+	ret, err := session.SendGroupMessage(m.PeerId, &[]milky.IMessageElement{&milky.TextElement{Text: "pong"}})
+	if err != nil {
+		t.Fatalf("SendGroupMessage: %v", err)
+	}
+	if ret.MessageSeq != 42 {
+		t.Fatalf("unexpected response %+v", ret)
+	}
+
+	calls := server.Calls(milky.EndpointSendGroupMessage)
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(calls))
+	}
+	var payload struct {
+		GroupID int64 `json:"group_id"`
+		Message []struct {
+			Type string `json:"type"`
+		} `json:"message"`
+	}
+	if err = calls[0].Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.GroupID != 100 || len(payload.Message) != 1 || payload.Message[0].Type != "text" {
+		t.Fatalf("unexpected payload %s", calls[0].Payload)
+	}
+	if got := calls[0].Header.Get("Authorization"); got != "Bearer secret" {
+		t.Fatalf("unexpected Authorization header %q", got)
+	}
+}
+
+func TestServerFailureInjection(t *testing.T) {
+	server := milkytest.NewServer(t)
+	session := server.NewSession(t)
+
+	server.FailNext(milky.EndpointGetLoginInfo, http.StatusBadGateway, 2)
+	server.Respond(milky.EndpointGetLoginInfo, milky.LoginInfo{UIN: 10001, Nickname: "bot"})
+	info, err := session.GetLoginInfo()
+	if err != nil {
+		t.Fatalf("GetLoginInfo should succeed after retries: %v", err)
+	}
+	if info.UIN != 10001 || len(server.Calls(milky.EndpointGetLoginInfo)) != 3 {
+		t.Fatalf("unexpected result %+v after %d calls", info, len(server.Calls(milky.EndpointGetLoginInfo)))
+	}
+
+	server.RespondError(milky.EndpointKickGroupMember, 10403, "permission denied")
+	if err = session.KickGroupMember(1, 2, false); err == nil {
+		t.Fatal("expected KickGroupMember to fail")
+	}
+
+	server.Delay(milky.EndpointGetGroupList, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = session.GetGroupList(false, milky.WithContext(ctx)); err == nil {
+		t.Fatal("expected slow GetGroupList to time out")
+	}
+}
+
+func TestServerDisconnect(t *testing.T) {
+	server := milkytest.NewServer(t)
+	session := server.NewSession(t)
+	if err := session.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := server.WaitForConnections(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Disconnect()
+	if err := server.WaitForConnections(1, 5*time.Second); err != nil {
+		t.Fatalf("session did not reconnect: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultRoleCacheTTL is how long group roles are cached when
+// Session.RoleCacheTTL is 0.
+const DefaultRoleCacheTTL = 5 * time.Minute
+
+// ErrInsufficientPermission is matched by errors.Is for every PermissionError.
+var ErrInsufficientPermission = errors.New("insufficient permission")
+
+// ErrTargetIsOwner is returned, without sending the request, for operations
+// on the group owner, which no role can perform.
+var ErrTargetIsOwner = errors.New("target is the group owner")
+
+// PermissionError is returned by admin operations, without sending the
+// request, when Session.CheckPermissions is set and the bot's role in the
+// group is too low.
+type PermissionError struct {
+	Endpoint string
+	GroupID  int64
+	UserID   int64 // the target member, 0 for operations on the whole group
+
+	Required   Role // the role the bot needs
+	Role       Role // the role the bot has
+	TargetRole Role // the target member's role, when there is one
+}
+
+func (e *PermissionError) Error() string {
+	msg := fmt.Sprintf("%s in group %d requires role %s, bot is %s", e.Endpoint, e.GroupID, e.Required, e.Role)
+	if e.UserID != 0 {
+		msg += fmt.Sprintf(", target %d is %s", e.UserID, e.TargetRole)
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrInsufficientPermission) true.
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrInsufficientPermission
+}
+
+type roleKey struct {
+	groupID, userID int64
+}
+
+type roleEntry struct {
+	role    Role
+	expires time.Time
+}
+
+// roleCache caches group roles for permission checks.
+type roleCache struct {
+	sync.Mutex
+	entries map[roleKey]roleEntry
+}
+
+func (c *roleCache) get(groupID, userID int64) (Role, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[roleKey{groupID, userID}]
+	if !ok || time.Now().After(e.expires) {
+		return "", false
+	}
+	return e.role, true
+}
+
+func (c *roleCache) set(groupID, userID int64, role Role, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = map[roleKey]roleEntry{}
+	}
+	c.entries[roleKey{groupID, userID}] = roleEntry{role: role, expires: time.Now().Add(ttl)}
+}
+
+func (c *roleCache) delete(groupID, userID int64) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, roleKey{groupID, userID})
+}
+
+func (s *Session) roleCacheTTL() time.Duration {
+	if s.RoleCacheTTL > 0 {
+		return s.RoleCacheTTL
+	}
+	return DefaultRoleCacheTTL
+}
+
+// MemberRole returns the role of userID in groupID, from the role cache when
+// possible.
+func (s *Session) MemberRole(groupID, userID int64, options ...RequestOption) (Role, error) {
+	if role, ok := s.roles.get(groupID, userID); ok {
+		return role, nil
+	}
+	member, err := s.GetGroupMemberInfo(groupID, userID, false, options...)
+	if err != nil {
+		return "", err
+	}
+	s.roles.set(groupID, userID, member.Role, s.roleCacheTTL())
+	return member.Role, nil
+}
+
+// InvalidateRole drops the cached role of userID in groupID.
+func (s *Session) InvalidateRole(groupID, userID int64) {
+	s.roles.delete(groupID, userID)
+}
+
+// observeRoles keeps the role cache current from received events.
+func (s *Session) observeRoles(event interface{}) {
+	switch e := event.(type) {
+	case *ReceiveMessage:
+		if e.GroupMember != nil && e.GroupMember.Role.IsValid() {
+			s.roles.set(e.GroupMember.GroupId, e.GroupMember.UserId, e.GroupMember.Role, s.roleCacheTTL())
+		}
+	case *GroupAdminChange:
+		role := RoleMember
+		if e.IsSet {
+			role = RoleAdmin
+		}
+		s.roles.set(e.GroupID, e.UserID, role, s.roleCacheTTL())
+	case *GroupMemberDecrease:
+		s.roles.delete(e.GroupID, e.UserID)
+	}
+}
+
+// selfRole returns the bot's role in groupID. ok is false when the check
+// cannot be made, because permission checks are off, the bot's UIN is not
+// known yet or the lookup failed.
+func (s *Session) selfRole(endpoint string, groupID int64, options []RequestOption) (role Role, ok bool) {
+	if !s.CheckPermissions {
+		return "", false
+	}
+	s.RLock()
+	self := s.Self
+	s.RUnlock()
+	if self == nil {
+		s.log().Debugf("skipping permission check of %s, the bot's UIN is not known", endpoint)
+		return "", false
+	}
+	role, err := s.MemberRole(groupID, self.UIN, options...)
+	if err != nil {
+		s.log().Warnf("skipping permission check of %s, %s", endpoint, err)
+		return "", false
+	}
+	return role, true
+}
+
+// checkRole fails when the bot's role in groupID is below required.
+func (s *Session) checkRole(endpoint string, groupID int64, required Role, options []RequestOption) error {
+	role, ok := s.selfRole(endpoint, groupID, options)
+	if !ok || role.rank() >= required.rank() {
+		return nil
+	}
+	return &PermissionError{Endpoint: endpoint, GroupID: groupID, Required: required, Role: role}
+}
+
+// checkManage fails when the bot's role in groupID does not outrank the
+// role of userID, or with ErrTargetIsOwner when userID owns the group.
+func (s *Session) checkManage(endpoint string, groupID, userID int64, options []RequestOption) error {
+	role, ok := s.selfRole(endpoint, groupID, options)
+	if !ok {
+		return nil
+	}
+	target, err := s.MemberRole(groupID, userID, options...)
+	if err != nil {
+		s.log().Warnf("skipping permission check of %s, %s", endpoint, err)
+		return nil
+	}
+	if target == RoleOwner {
+		return fmt.Errorf("%s in group %d on member %d: %w", endpoint, groupID, userID, ErrTargetIsOwner)
+	}
+	if role.CanManage(target) {
+		return nil
+	}
+	required := RoleAdmin
+	if target != RoleMember {
+		required = RoleOwner
+	}
+	return &PermissionError{Endpoint: endpoint, GroupID: groupID, UserID: userID, Required: required, Role: role, TargetRole: target}
+}
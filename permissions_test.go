@@ -0,0 +1,72 @@
+package Milky_go_sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPermissionChecks(t *testing.T) {
+	roles := map[int64]Role{1: RoleAdmin, 2: RoleMember, 3: RoleAdmin, 4: RoleOwner}
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := strings.TrimPrefix(r.URL.Path, "/")
+		calls[endpoint]++
+		var req struct {
+			UserID int64 `json:"user_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data := "{}"
+		if endpoint == EndpointGetGroupMemberInfo {
+			data = fmt.Sprintf(`{"member":{"group_id":10,"user_id":%d,"role":%q}}`, req.UserID, roles[req.UserID])
+		}
+		fmt.Fprintf(w, `{"status":"ok","retcode":0,"data":%s}`, data)
+	}))
+	defer server.Close()
+
+	s, _ := New("", server.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.CheckPermissions = true
+	s.Self = &SelfUser{UIN: 1}
+
+	if err := s.KickGroupMember(10, 2, false); err != nil {
+		t.Fatalf("admin kicking a member: %v", err)
+	}
+
+	err := s.SetGroupMemberMute(10, 3, 60)
+	var permErr *PermissionError
+	if !errors.Is(err, ErrInsufficientPermission) || !errors.As(err, &permErr) || permErr.Required != RoleOwner || permErr.TargetRole != RoleAdmin {
+		t.Fatalf("admin muting an admin: expected a PermissionError requiring owner, got %v", err)
+	}
+	if err = s.SetGroupMemberSpecialTitle(10, 2, "title"); !errors.Is(err, ErrInsufficientPermission) {
+		t.Fatalf("admin setting a title: expected ErrInsufficientPermission, got %v", err)
+	}
+	if calls[EndpointSetGroupMemberMute] != 0 || calls[EndpointSetGroupMemberSpecialTitle] != 0 {
+		t.Errorf("requests sent despite insufficient permission: %v", calls)
+	}
+	// The bot's role and each target's role were looked up once.
+	if calls[EndpointGetGroupMemberInfo] != 3 {
+		t.Errorf("got %d role lookups, want 3", calls[EndpointGetGroupMemberInfo])
+	}
+
+	// Nobody can manage the owner, whatever the bot's role.
+	err = s.KickGroupMember(10, 4, false)
+	if !errors.Is(err, ErrTargetIsOwner) || errors.Is(err, ErrInsufficientPermission) || calls[EndpointKickGroupMember] != 1 {
+		t.Errorf("kicking the owner: expected ErrTargetIsOwner, got %v", err)
+	}
+
+	// Losing admin is picked up from the event without another lookup.
+	s.observeRoles(&GroupAdminChange{GroupID: 10, UserID: 1, IsSet: false})
+	if err = s.SetGroupWholeMute(10, true); !errors.Is(err, ErrInsufficientPermission) {
+		t.Errorf("member muting the group: expected ErrInsufficientPermission, got %v", err)
+	}
+
+	s.CheckPermissions = false
+	if err = s.SetGroupWholeMute(10, true); err != nil || calls[EndpointSetGroupWholeMute] != 1 {
+		t.Errorf("unchecked call: %v, %d requests", err, calls[EndpointSetGroupWholeMute])
+	}
+}
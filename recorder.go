@@ -0,0 +1,203 @@
+package Milky_go_sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FrameRecorder receives every raw event frame a Session reads from the
+// websocket, after decompression and before dispatch.
+type FrameRecorder interface {
+	RecordFrame(t time.Time, frame []byte)
+}
+
+// RecordedFrame is one line of a recording. Frames that are not valid JSON
+// are kept byte for byte in Raw, which is base64 encoded, instead of Frame.
+type RecordedFrame struct {
+	Time  time.Time       `json:"time"`
+	Frame json.RawMessage `json:"frame,omitempty"`
+	Raw   []byte          `json:"raw,omitempty"`
+}
+
+// Recorder writes event frames as JSON lines, one RecordedFrame per line.
+// Assign it to Session.Recorder to start recording.
+type Recorder struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	closer  io.Closer
+	types   map[string]bool
+	err     error
+	counter int
+}
+
+// NewRecorder returns a Recorder writing to w. When eventTypes are given,
+// only events of those types are recorded.
+func NewRecorder(w io.Writer, eventTypes ...string) *Recorder {
+	r := &Recorder{enc: json.NewEncoder(w)}
+	if c, ok := w.(io.Closer); ok {
+		r.closer = c
+	}
+	if len(eventTypes) > 0 {
+		r.types = map[string]bool{}
+		for _, t := range eventTypes {
+			r.types[t] = true
+		}
+	}
+	return r
+}
+
+// CreateRecording creates or truncates the file at path and returns a
+// Recorder writing to it.
+func CreateRecording(path string, eventTypes ...string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecorder(f, eventTypes...), nil
+}
+
+// RecordFrame implements FrameRecorder. Write errors are kept and reported
+// by Err and Close, as the websocket read loop cannot act on them.
+func (r *Recorder) RecordFrame(t time.Time, frame []byte) {
+	if r.types != nil && !r.types[eventTypeOf(frame)] {
+		return
+	}
+	rf := RecordedFrame{Time: t}
+	if json.Valid(frame) {
+		rf.Frame = frame
+	} else {
+		rf.Raw = frame
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(rf)
+	r.counter++
+}
+
+// Count returns the number of frames recorded so far.
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counter
+}
+
+// Err returns the first write error, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closer != nil {
+		if err := r.closer.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// Replayer feeds a recording back into a Session's event dispatch without a
+// network connection. Set Session.SyncEvents for handlers to run in the
+// recorded order.
+type Replayer struct {
+	Session *Session
+
+	// Speed scales the recorded gaps between frames: 1 replays in real
+	// time, 2 twice as fast. 0 replays as fast as possible.
+	Speed float64
+
+	// When set, only events of these types are replayed.
+	EventTypes []string
+}
+
+// NewReplayer returns a Replayer that replays as fast as possible.
+func NewReplayer(s *Session, eventTypes ...string) *Replayer {
+	return &Replayer{Session: s, EventTypes: eventTypes}
+}
+
+// Replay dispatches every frame read from r and returns how many frames
+// were dispatched. Frames that were recorded as raw bytes are skipped.
+func (p *Replayer) Replay(ctx context.Context, r io.Reader) (int, error) {
+	var types map[string]bool
+	if len(p.EventTypes) > 0 {
+		types = map[string]bool{}
+		for _, t := range p.EventTypes {
+			types[t] = true
+		}
+	}
+
+	var (
+		dispatched int
+		last       time.Time
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxFileSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rf RecordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &rf); err != nil {
+			return dispatched, err
+		}
+		if rf.Raw != nil {
+			p.Session.log().Warnf("skipping recorded frame from %s that is not valid JSON", rf.Time.Format(time.RFC3339))
+			continue
+		}
+		if types != nil && !types[eventTypeOf(rf.Frame)] {
+			continue
+		}
+
+		if p.Speed > 0 && !last.IsZero() {
+			if gap := rf.Time.Sub(last); gap > 0 {
+				select {
+				case <-time.After(time.Duration(float64(gap) / p.Speed)):
+				case <-ctx.Done():
+					return dispatched, ctx.Err()
+				}
+			}
+		}
+		last = rf.Time
+
+		if err := ctx.Err(); err != nil {
+			return dispatched, err
+		}
+		if _, err := p.Session.dispatchFrame(rf.Frame); err != nil {
+			return dispatched, err
+		}
+		dispatched++
+	}
+	return dispatched, scanner.Err()
+}
+
+// ReplayFile replays the recording at path.
+func (p *Replayer) ReplayFile(ctx context.Context, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return p.Replay(ctx, f)
+}
+
+// eventTypeOf returns the event_type of a raw event frame.
+func eventTypeOf(frame []byte) string {
+	var e struct {
+		Type string `json:"event_type"`
+	}
+	_ = json.Unmarshal(frame, &e)
+	return e.Type
+}
@@ -0,0 +1,56 @@
+package Milky_go_sdk
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	frames := []string{
+		`{"event_type":"group_nudge","data":{"group_id":1,"sender_id":2,"receiver_id":3}}`,
+		`{"event_type":"message_receive","data":{"peer_id":1,"message_seq":10,"sender_id":2,"time":1,"message_scene":"group","segments":[]}}`,
+		`{"event_type":"message_receive","data":{"peer_id":1,"message_seq":11,"sender_id":2,"time":2,"message_scene":"group","segments":[]}}`,
+	}
+
+	var recording bytes.Buffer
+	live, _ := New("", "", "", &TestLogger{})
+	live.SyncEvents = true
+	live.Recorder = NewRecorder(&recording)
+	for _, frame := range frames {
+		if _, err := live.onEvent(websocket.TextMessage, []byte(frame)); err != nil {
+			t.Fatalf("onEvent: %v", err)
+		}
+	}
+	// A frame that is not JSON is recorded raw and skipped on replay.
+	live.Recorder.RecordFrame(time.Now(), []byte("\x00not json"))
+	if n := live.Recorder.(*Recorder).Count(); n != len(frames)+1 {
+		t.Fatalf("recorded %d frames, want %d", n, len(frames)+1)
+	}
+
+	replay, _ := New("", "", "", &TestLogger{})
+	replay.SyncEvents = true
+	var seqs []int64
+	nudges := 0
+	replay.AddHandler(func(s *Session, m *ReceiveMessage) {
+		seqs = append(seqs, m.MessageSeq)
+	})
+	replay.AddHandler(func(s *Session, n *GroupNudge) {
+		nudges++
+	})
+
+	n, err := NewReplayer(replay, messageReceiveEventType).Replay(context.Background(), bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if n != 2 || nudges != 0 || len(seqs) != 2 || seqs[0] != 10 || seqs[1] != 11 {
+		t.Fatalf("replayed %d frames: seqs %v, nudges %d", n, seqs, nudges)
+	}
+
+	if n, err = NewReplayer(replay).Replay(context.Background(), bytes.NewReader(recording.Bytes())); err != nil || n != len(frames) {
+		t.Fatalf("full replay dispatched %d frames, %v; want %d", n, err, len(frames))
+	}
+}
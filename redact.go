@@ -0,0 +1,80 @@
+package Milky_go_sdk
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces secrets in log output.
+const RedactedValue = "[REDACTED]"
+
+// DefaultSensitiveFields are the header, query parameter and JSON field names
+// whose values are always masked in log output. Session.SensitiveFields adds
+// to them.
+var DefaultSensitiveFields = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"access_token",
+	"cookies",
+	"csrf_token",
+}
+
+// redactor masks the values of a set of field names in log lines.
+type redactor struct {
+	header *regexp.Regexp // [Name] = [value], as RequestBase logs headers
+	json   *regexp.Regexp // "name": "value"
+	query  *regexp.Regexp // ?name=value and &name=value
+}
+
+var (
+	redactorsMu sync.Mutex
+	redactors   = map[string]*redactor{}
+)
+
+// redactorFor returns the redactor for the default fields plus extra,
+// compiling it on first use.
+func redactorFor(extra []string) *redactor {
+	names := make([]string, 0, len(DefaultSensitiveFields)+len(extra))
+	for _, name := range append(DefaultSensitiveFields[:len(DefaultSensitiveFields):len(DefaultSensitiveFields)], extra...) {
+		if name != "" {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+	sort.Strings(names)
+	key := strings.Join(names, "|")
+
+	redactorsMu.Lock()
+	defer redactorsMu.Unlock()
+	if r, ok := redactors[key]; ok {
+		return r
+	}
+	r := &redactor{
+		header: regexp.MustCompile(`(?i)(\[(?:` + key + `)\] = )\[[^\]]*\]`),
+		json:   regexp.MustCompile(`(?i)("(?:` + key + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`),
+		query:  regexp.MustCompile(`(?i)([?&](?:` + key + `)=)[^&\s"]*`),
+	}
+	redactors[key] = r
+	return r
+}
+
+// Redact masks the values of sensitive fields and any of the literal secrets
+// in msg.
+func (r *redactor) Redact(msg string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, RedactedValue)
+		}
+	}
+	msg = r.header.ReplaceAllString(msg, "${1}"+RedactedValue)
+	msg = r.json.ReplaceAllString(msg, `${1}"`+RedactedValue+`"`)
+	return r.query.ReplaceAllString(msg, "${1}"+RedactedValue)
+}
+
+// Redact masks the session token and the values of sensitive fields in msg,
+// the same way the session's own log output is masked.
+func (s *Session) Redact(msg string) string {
+	return redactorFor(s.SensitiveFields).Redact(msg, s.Token)
+}
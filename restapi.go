@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 var (
@@ -109,6 +111,9 @@ func WithContext(ctx context.Context) RequestOption {
 
 // Request makes a (GET/POST/...) Requests to REST API with JSON data.
 func (s *Session) Request(method string, pathStr string, data interface{}, options ...RequestOption) (response []byte, err error) {
+	if err = s.checkEndpoint(pathStr); err != nil {
+		return
+	}
 	var body []byte
 	if data != nil {
 		body, err = Marshal(data)
@@ -116,14 +121,16 @@ func (s *Session) Request(method string, pathStr string, data interface{}, optio
 			return
 		}
 	}
-	return s.RequestBase(method, s.apiEndpoints.Endpoint(pathStr), "application/json", body, 0, options...)
+	response, err = s.RequestBase(method, s.endpoint(pathStr), "application/json", body, 0, options...)
+	if err != nil {
+		err = s.unsupportedResponse(pathStr, err)
+	}
+	return
 }
 
 // RequestBase makes a request
 func (s *Session) RequestBase(method, urlStr, contentType string, b []byte, sequence int, options ...RequestOption) (response []byte, err error) {
-
-	s.Logger.Debugf("API REQUEST %8s :: %s\n", method, urlStr)
-	s.Logger.Debugf("API REQUEST  PAYLOAD :: [%s]\n", string(b))
+	endpoint := endpointOf(urlStr)
 
 	req, err := http.NewRequest(method, urlStr, bytes.NewBuffer(b))
 	if err != nil {
@@ -144,20 +151,68 @@ func (s *Session) RequestBase(method, urlStr, contentType string, b []byte, sequ
 	for _, opt := range options {
 		opt(cfg)
 	}
-	req = cfg.Request
+	ctx, span := s.tracer().Start(cfg.Request.Context(), "milky.rest "+endpoint)
+	cfg.Request = cfg.Request.WithContext(ctx)
+
+	start := time.Now()
+	response, status, last, err := s.request(cfg, b, sequence)
+	m := RequestMetric{
+		Endpoint: endpoint,
+		Status:   status,
+		Latency:  time.Since(start),
+		Retries:  last - sequence,
+		Err:      err,
+	}
+	// A failed API call still answers with HTTP 200, the span ends with the
+	// error the API method will return.
+	spanErr := err
+	if status == http.StatusOK && s.instrumented() {
+		var apiResponse APIResponse
+		apiErr := handleAPIResponse(response, &apiResponse, nil)
+		m.RetCode = apiResponse.RetCode
+		if spanErr == nil {
+			spanErr = apiErr
+		}
+	}
+
+	span.SetAttribute("milky.endpoint", endpoint)
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.status_code", status)
+	span.SetAttribute("milky.retcode", m.RetCode)
+	span.SetAttribute("milky.retries", m.Retries)
+	span.End(spanErr)
+
+	s.metrics().ObserveRequest(m)
+	return
+}
+
+// request sends the request configured by cfg, retrying on bad gateway. It
+// also returns the HTTP status and sequence number of the last attempt.
+func (s *Session) request(cfg *RequestConfig, b []byte, sequence int) (response []byte, status int, last int, err error) {
+	last = sequence
+	urlStr := cfg.Request.URL.String()
+	log := s.log().With("endpoint", endpointOf(urlStr), "attempt", sequence)
+
+	log.Debugf("API REQUEST %8s :: %s\n", cfg.Request.Method, urlStr)
+	log.Debugf("API REQUEST  PAYLOAD :: [%s]\n", string(b))
+
+	// Every attempt sends a copy of the request with a fresh body.
+	req := cfg.Request.Clone(cfg.Request.Context())
+	req.Body = io.NopCloser(bytes.NewReader(b))
 
 	for k, v := range req.Header {
-		s.Logger.Debugf("API REQUEST   HEADER :: [%s] = %+v\n", k, v)
+		log.Debugf("API REQUEST   HEADER :: [%s] = %+v\n", k, v)
 	}
 
 	resp, err := cfg.Client.Do(req)
 	if err != nil {
 		return
 	}
+	status = resp.StatusCode
 	defer func() {
 		err2 := resp.Body.Close()
 		if err2 != nil {
-			s.Logger.Debugf("error closing resp body")
+			log.Debugf("error closing resp body")
 		}
 	}()
 
@@ -166,11 +221,11 @@ func (s *Session) RequestBase(method, urlStr, contentType string, b []byte, sequ
 		return
 	}
 
-	s.Logger.Debugf("API RESPONSE  STATUS :: %s\n", resp.Status)
+	log.Debugf("API RESPONSE  STATUS :: %s\n", resp.Status)
 	for k, v := range resp.Header {
-		s.Logger.Debugf("API RESPONSE  HEADER :: [%s] = %+v\n", k, v)
+		log.Debugf("API RESPONSE  HEADER :: [%s] = %+v\n", k, v)
 	}
-	s.Logger.Debugf("API RESPONSE    BODY :: [%s]\n\n\n", response)
+	log.Debugf("API RESPONSE    BODY :: [%s]\n\n\n", response)
 
 	switch resp.StatusCode {
 	case http.StatusOK:
@@ -180,13 +235,13 @@ func (s *Session) RequestBase(method, urlStr, contentType string, b []byte, sequ
 		// Retry sending request if possible
 		if sequence < cfg.MaxRestRetries {
 
-			s.Logger.Infof("%s Failed (%s), Retrying...", urlStr, resp.Status)
-			response, err = s.RequestBase(method, urlStr, contentType, b, sequence+1, options...)
+			log.Infof("%s Failed (%s), Retrying...", urlStr, resp.Status)
+			response, status, last, err = s.request(cfg, b, sequence+1)
 		} else {
 			err = fmt.Errorf("exceeded Max retries HTTP %s, %s", resp.Status, response)
 		}
 	case http.StatusUnauthorized:
-		s.Logger.Warnf(ErrUnauthorized.Error())
+		log.Warnf(ErrUnauthorized.Error())
 		err = ErrUnauthorized
 		fallthrough
 	default: // Error condition
@@ -196,6 +251,11 @@ func (s *Session) RequestBase(method, urlStr, contentType string, b []byte, sequ
 	return
 }
 
+// endpointOf returns the API endpoint name, the last path element of urlStr.
+func endpointOf(urlStr string) string {
+	return urlStr[strings.LastIndex(urlStr, "/")+1:]
+}
+
 func unmarshal(data []byte, v interface{}) error {
 	err := Unmarshal(data, v)
 	if err != nil {
@@ -218,10 +278,10 @@ func handleAPIResponse(request []byte, apiResponse *APIResponse, data interface{
 	return nil
 }
 
-func (s *Session) GetLoginInfo() (*LoginInfo, error) {
+func (s *Session) GetLoginInfo(options ...RequestOption) (*LoginInfo, error) {
 	var apiResponse APIResponse
 	var loginInfo LoginInfo
-	request, err := s.Request("POST", EndpointGetLoginInfo, struct{}{})
+	request, err := s.Request("POST", EndpointGetLoginInfo, struct{}{}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -231,8 +291,8 @@ func (s *Session) GetLoginInfo() (*LoginInfo, error) {
 	return &loginInfo, nil
 }
 
-func (s *Session) GetImplInfo() (*ImplInfo, error) {
-	request, err := s.Request("POST", EndpointGetImplInfo, struct{}{})
+func (s *Session) GetImplInfo(options ...RequestOption) (*ImplInfo, error) {
+	request, err := s.Request("POST", EndpointGetImplInfo, struct{}{}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -244,10 +304,10 @@ func (s *Session) GetImplInfo() (*ImplInfo, error) {
 	return &implInfo, nil
 }
 
-func (s *Session) GetUserProfile(userID int64) (*UserProfile, error) {
+func (s *Session) GetUserProfile(userID int64, options ...RequestOption) (*UserProfile, error) {
 	request, err := s.Request("POST", EndpointGetUserProfile, map[string]interface{}{
 		"user_id": userID,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -259,10 +319,10 @@ func (s *Session) GetUserProfile(userID int64) (*UserProfile, error) {
 	return &userProfile, nil
 }
 
-func (s *Session) GetFriendList(noCache bool) ([]Friend, error) {
+func (s *Session) GetFriendList(noCache bool, options ...RequestOption) ([]Friend, error) {
 	request, err := s.Request("POST", EndpointGetFriendList, map[string]interface{}{
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -276,11 +336,11 @@ func (s *Session) GetFriendList(noCache bool) ([]Friend, error) {
 	return friendList.Friends, nil
 }
 
-func (s *Session) GetFriendInfo(userID int64, noCache bool) (*Friend, error) {
+func (s *Session) GetFriendInfo(userID int64, noCache bool, options ...RequestOption) (*Friend, error) {
 	request, err := s.Request("POST", EndpointGetFriendInfo, map[string]interface{}{
 		"user_id":  userID,
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -294,10 +354,10 @@ func (s *Session) GetFriendInfo(userID int64, noCache bool) (*Friend, error) {
 	return &friendInfo.Friend, nil
 }
 
-func (s *Session) GetGroupList(noCache bool) ([]GroupInfo, error) {
+func (s *Session) GetGroupList(noCache bool, options ...RequestOption) ([]GroupInfo, error) {
 	request, err := s.Request("POST", EndpointGetGroupList, map[string]interface{}{
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -311,11 +371,11 @@ func (s *Session) GetGroupList(noCache bool) ([]GroupInfo, error) {
 	return groupList.Groups, nil
 }
 
-func (s *Session) GetGroupInfo(groupID int64, noCache bool) (*GroupInfo, error) {
+func (s *Session) GetGroupInfo(groupID int64, noCache bool, options ...RequestOption) (*GroupInfo, error) {
 	request, err := s.Request("POST", EndpointGetGroupInfo, map[string]interface{}{
 		"group_id": groupID,
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -329,11 +389,11 @@ func (s *Session) GetGroupInfo(groupID int64, noCache bool) (*GroupInfo, error)
 	return &groupInfo.Group, nil
 }
 
-func (s *Session) GetGroupMemberList(groupID int64, noCache bool) ([]GroupMemberInfo, error) {
+func (s *Session) GetGroupMemberList(groupID int64, noCache bool, options ...RequestOption) ([]GroupMemberInfo, error) {
 	request, err := s.Request("POST", EndpointGetGroupMemberList, map[string]interface{}{
 		"group_id": groupID,
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -347,12 +407,12 @@ func (s *Session) GetGroupMemberList(groupID int64, noCache bool) ([]GroupMember
 	return memberList.Members, nil
 }
 
-func (s *Session) GetGroupMemberInfo(groupID, userID int64, noCache bool) (*GroupMemberInfo, error) {
+func (s *Session) GetGroupMemberInfo(groupID, userID int64, noCache bool, options ...RequestOption) (*GroupMemberInfo, error) {
 	request, err := s.Request("POST", EndpointGetGroupMemberInfo, map[string]interface{}{
 		"group_id": groupID,
 		"user_id":  userID,
 		"no_cache": noCache,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -366,10 +426,10 @@ func (s *Session) GetGroupMemberInfo(groupID, userID int64, noCache bool) (*Grou
 	return &memberInfo.Member, nil
 }
 
-func (s *Session) GetCookies(domain string) (string, error) {
+func (s *Session) GetCookies(domain string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointGetCookies, map[string]interface{}{
 		"domain": domain,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -383,8 +443,8 @@ func (s *Session) GetCookies(domain string) (string, error) {
 	return cookiesResponse.Cookies, nil
 }
 
-func (s *Session) GetCSRFToken() (string, error) {
-	request, err := s.Request("POST", EndpointGetCSRFToken, struct{}{})
+func (s *Session) GetCSRFToken(options ...RequestOption) (string, error) {
+	request, err := s.Request("POST", EndpointGetCSRFToken, struct{}{}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -398,46 +458,49 @@ func (s *Session) GetCSRFToken() (string, error) {
 	return csrfResponse.CSRFToken, nil
 }
 
-func (s *Session) SendGroupMessage(groupID int64, message *[]IMessageElement) (*MessageRet, error) {
+func (s *Session) SendGroupMessage(groupID int64, message *[]IMessageElement, options ...RequestOption) (*MessageRet, error) {
 	request, err := s.Request("POST", EndpointSendGroupMessage, map[string]interface{}{
 		"group_id": groupID,
 		"message":  message,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
 	var apiResponse APIResponse
 	var messageRet MessageRet
 	if err = handleAPIResponse(request, &apiResponse, &messageRet); err != nil {
-		s.Logger.Errorf("Failed to unmarshal send group message response: %v", err)
+		s.log().Errorf("Failed to unmarshal send group message response: %v", err)
 		return nil, err
 	}
 	return &messageRet, nil
 }
 
-func (s *Session) SendPrivateMessage(userID int64, message *[]IMessageElement) (*MessageRet, error) {
+func (s *Session) SendPrivateMessage(userID int64, message *[]IMessageElement, options ...RequestOption) (*MessageRet, error) {
 	request, err := s.Request("POST", EndpointSendPrivateMessage, map[string]interface{}{
 		"user_id": userID,
 		"message": message,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
 	var apiResponse APIResponse
 	var messageRet MessageRet
 	if err = handleAPIResponse(request, &apiResponse, &messageRet); err != nil {
-		s.Logger.Errorf("Failed to unmarshal send private message response: %v", err)
+		s.log().Errorf("Failed to unmarshal send private message response: %v", err)
 		return nil, err
 	}
 	return &messageRet, nil
 }
 
-func (s *Session) GetMessage(messageScene string, peerID int64, messageSeq int64) (*ReceiveMessage, error) {
+func (s *Session) GetMessage(messageScene MessageScene, peerID int64, messageSeq int64, options ...RequestOption) (*ReceiveMessage, error) {
+	if err := validMessageScene(messageScene); err != nil {
+		return nil, err
+	}
 	request, err := s.Request("POST", EndpointGetMessage, map[string]interface{}{
 		"message_scene": messageScene,
 		"peer_id":       peerID,
 		"message_seq":   messageSeq,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -449,13 +512,20 @@ func (s *Session) GetMessage(messageScene string, peerID int64, messageSeq int64
 	return &receiveMessage, nil
 }
 
-func (s *Session) GetHistoryMessages(messageScene string, peerID int64, startMessageSeq int64, limit int32) (msg []ReceiveMessage, nextMessageSeq int64, err error) {
-	request, err := s.Request("POST", EndpointGetHistoryMessages, map[string]interface{}{
-		"message_scene":     messageScene,
-		"peer_id":           peerID,
-		"start_message_seq": startMessageSeq,
-		"limit":             limit,
-	})
+func (s *Session) GetHistoryMessages(messageScene MessageScene, peerID int64, startMessageSeq int64, limit int32, options ...RequestOption) (msg []ReceiveMessage, nextMessageSeq int64, err error) {
+	if err := validMessageScene(messageScene); err != nil {
+		return nil, 0, err
+	}
+	data := map[string]interface{}{
+		"message_scene": messageScene,
+		"peer_id":       peerID,
+		"limit":         limit,
+	}
+	// start_message_seq is optional, leaving it out starts from the latest message.
+	if startMessageSeq > 0 {
+		data["start_message_seq"] = startMessageSeq
+	}
+	request, err := s.Request("POST", EndpointGetHistoryMessages, data, options...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -470,10 +540,10 @@ func (s *Session) GetHistoryMessages(messageScene string, peerID int64, startMes
 	return historyMessages.Messages, historyMessages.NextMessageSeq, nil
 }
 
-func (s *Session) GetResourceTempURL(resourceID string) (string, error) {
+func (s *Session) GetResourceTempURL(resourceID string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointGetResourceTempURL, map[string]interface{}{
 		"resource_id": resourceID,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -487,10 +557,10 @@ func (s *Session) GetResourceTempURL(resourceID string) (string, error) {
 	return tempURLResponse.URL, nil
 }
 
-func (s *Session) GetForwardedMessages(forwardID string) ([]ReceiveMessage, error) {
+func (s *Session) GetForwardedMessages(forwardID string, options ...RequestOption) ([]ReceiveMessage, error) {
 	request, err := s.Request("POST", EndpointGetForwardedMessages, map[string]interface{}{
 		"forward_id": forwardID,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -504,11 +574,11 @@ func (s *Session) GetForwardedMessages(forwardID string) ([]ReceiveMessage, erro
 	return forwardedMessages.Messages, nil
 }
 
-func (s *Session) RecallPrivateMessage(userID int64, messageSeq int64) error {
+func (s *Session) RecallPrivateMessage(userID int64, messageSeq int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRecallPrivateMessage, map[string]interface{}{
 		"user_id":     userID,
 		"message_seq": messageSeq,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -516,11 +586,11 @@ func (s *Session) RecallPrivateMessage(userID int64, messageSeq int64) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) RecallGroupMessage(groupID int64, messageSeq int64) error {
+func (s *Session) RecallGroupMessage(groupID int64, messageSeq int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRecallGroupMessage, map[string]interface{}{
 		"group_id":    groupID,
 		"message_seq": messageSeq,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -528,12 +598,15 @@ func (s *Session) RecallGroupMessage(groupID int64, messageSeq int64) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) MarkMessageAsRead(messageScene string, peerID int64, messageSeq int64) error {
+func (s *Session) MarkMessageAsRead(messageScene MessageScene, peerID int64, messageSeq int64, options ...RequestOption) error {
+	if err := validMessageScene(messageScene); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointMarkMessageAsRead, map[string]interface{}{
 		"message_scene": messageScene,
 		"peer_id":       peerID,
 		"message_seq":   messageSeq,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -541,11 +614,11 @@ func (s *Session) MarkMessageAsRead(messageScene string, peerID int64, messageSe
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SendFriendNudge(userID int64, isSelf bool) error {
+func (s *Session) SendFriendNudge(userID int64, isSelf bool, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSendFriendNudge, map[string]interface{}{
 		"user_id": userID,
 		"is_self": isSelf,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -553,11 +626,11 @@ func (s *Session) SendFriendNudge(userID int64, isSelf bool) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SendProfileLike(userID int64, count int32) error {
+func (s *Session) SendProfileLike(userID int64, count int32, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSendProfileLike, map[string]interface{}{
 		"user_id": userID,
 		"count":   count,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -565,11 +638,11 @@ func (s *Session) SendProfileLike(userID int64, count int32) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) GetFriendRequests(limit int32, isFiltered bool) ([]FriendRequest, error) {
+func (s *Session) GetFriendRequests(limit int32, isFiltered bool, options ...RequestOption) ([]FriendRequest, error) {
 	request, err := s.Request("POST", EndpointGetFriendRequests, map[string]interface{}{
 		"limit":       limit,
 		"is_filtered": isFiltered,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -583,11 +656,11 @@ func (s *Session) GetFriendRequests(limit int32, isFiltered bool) ([]FriendReque
 	return friendRequests.Requests, nil
 }
 
-func (s *Session) AcceptFriendRequest(initiatorUid string, isFiltered bool) error {
+func (s *Session) AcceptFriendRequest(initiatorUid string, isFiltered bool, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointAcceptFriendRequest, map[string]interface{}{
 		"is_filtered":   isFiltered,
 		"initiator_uid": initiatorUid,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -595,12 +668,12 @@ func (s *Session) AcceptFriendRequest(initiatorUid string, isFiltered bool) erro
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) RejectFriendRequest(initiatorUid string, isFiltered bool, reason string) error {
+func (s *Session) RejectFriendRequest(initiatorUid string, isFiltered bool, reason string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRejectFriendRequest, map[string]interface{}{
 		"initiator_uid": initiatorUid,
 		"is_filtered":   isFiltered,
 		"reason":        reason,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -608,11 +681,11 @@ func (s *Session) RejectFriendRequest(initiatorUid string, isFiltered bool, reas
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupName(groupID int64, newGroupName string) error {
+func (s *Session) SetGroupName(groupID int64, newGroupName string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSetGroupName, map[string]interface{}{
 		"group_id":       groupID,
 		"new_group_name": newGroupName,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -620,11 +693,11 @@ func (s *Session) SetGroupName(groupID int64, newGroupName string) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupAvatar(groupID int64, imageURI string) error {
+func (s *Session) SetGroupAvatar(groupID int64, imageURI string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSetGroupAvatar, map[string]interface{}{
 		"group_id":  groupID,
 		"image_uri": imageURI,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -632,12 +705,12 @@ func (s *Session) SetGroupAvatar(groupID int64, imageURI string) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupMemberCard(groupID int64, userID int64, card string) error {
+func (s *Session) SetGroupMemberCard(groupID int64, userID int64, card string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSetGroupMemberCard, map[string]interface{}{
 		"group_id": groupID,
 		"user_id":  userID,
 		"card":     card,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -645,12 +718,15 @@ func (s *Session) SetGroupMemberCard(groupID int64, userID int64, card string) e
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupMemberSpecialTitle(groupID int64, userID int64, specialTitle string) error {
+func (s *Session) SetGroupMemberSpecialTitle(groupID int64, userID int64, specialTitle string, options ...RequestOption) error {
+	if err := s.checkRole(EndpointSetGroupMemberSpecialTitle, groupID, RoleOwner, options); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointSetGroupMemberSpecialTitle, map[string]interface{}{
 		"group_id":      groupID,
 		"user_id":       userID,
 		"special_title": specialTitle,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -658,25 +734,39 @@ func (s *Session) SetGroupMemberSpecialTitle(groupID int64, userID int64, specia
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupMemberAdmin(groupID int64, userID int64, isSet bool) error {
+func (s *Session) SetGroupMemberAdmin(groupID int64, userID int64, isSet bool, options ...RequestOption) error {
+	if err := s.checkRole(EndpointSetGroupMemberAdmin, groupID, RoleOwner, options); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointSetGroupMemberAdmin, map[string]interface{}{
 		"group_id": groupID,
 		"user_id":  userID,
 		"is_set":   isSet,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
 	var apiResponse APIResponse
-	return handleAPIResponse(request, &apiResponse, nil)
+	if err = handleAPIResponse(request, &apiResponse, nil); err != nil {
+		return err
+	}
+	s.InvalidateRole(groupID, userID)
+	return nil
 }
 
-func (s *Session) SetGroupMemberMute(groupID int64, userID int64, duration int32) error {
+// SetGroupMemberMute mutes a member for duration seconds, 0 unmutes them.
+func (s *Session) SetGroupMemberMute(groupID int64, userID int64, duration int32, options ...RequestOption) error {
+	if err := validMuteDuration(time.Duration(duration) * time.Second); err != nil {
+		return err
+	}
+	if err := s.checkManage(EndpointSetGroupMemberMute, groupID, userID, options); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointSetGroupMemberMute, map[string]interface{}{
 		"group_id": groupID,
 		"user_id":  userID,
 		"duration": duration,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -684,11 +774,23 @@ func (s *Session) SetGroupMemberMute(groupID int64, userID int64, duration int32
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SetGroupWholeMute(groupID int64, isMute bool) error {
+// SetGroupMemberMuteDuration mutes a member for d, rounded up to whole
+// seconds. 0 unmutes them.
+func (s *Session) SetGroupMemberMuteDuration(groupID int64, userID int64, d time.Duration, options ...RequestOption) error {
+	if err := validMuteDuration(d); err != nil {
+		return err
+	}
+	return s.SetGroupMemberMute(groupID, userID, int32(seconds(d)), options...)
+}
+
+func (s *Session) SetGroupWholeMute(groupID int64, isMute bool, options ...RequestOption) error {
+	if err := s.checkRole(EndpointSetGroupWholeMute, groupID, RoleAdmin, options); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointSetGroupWholeMute, map[string]interface{}{
 		"group_id": groupID,
 		"is_mute":  isMute,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -696,23 +798,30 @@ func (s *Session) SetGroupWholeMute(groupID int64, isMute bool) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) KickGroupMember(groupID int64, userID int64, rejectAddRequest bool) error {
+func (s *Session) KickGroupMember(groupID int64, userID int64, rejectAddRequest bool, options ...RequestOption) error {
+	if err := s.checkManage(EndpointKickGroupMember, groupID, userID, options); err != nil {
+		return err
+	}
 	request, err := s.Request("POST", EndpointKickGroupMember, map[string]interface{}{
 		"group_id":           groupID,
 		"user_id":            userID,
 		"reject_add_request": rejectAddRequest,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
 	var apiResponse APIResponse
-	return handleAPIResponse(request, &apiResponse, nil)
+	if err = handleAPIResponse(request, &apiResponse, nil); err != nil {
+		return err
+	}
+	s.InvalidateRole(groupID, userID)
+	return nil
 }
 
-func (s *Session) GetGroupAnnouncementList(groupID int64) ([]GroupAnnouncement, error) {
+func (s *Session) GetGroupAnnouncementList(groupID int64, options ...RequestOption) ([]GroupAnnouncement, error) {
 	request, err := s.Request("POST", EndpointGetGroupAnnouncementList, map[string]interface{}{
 		"group_id": groupID,
-	})
+	}, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -726,12 +835,12 @@ func (s *Session) GetGroupAnnouncementList(groupID int64) ([]GroupAnnouncement,
 	return announcementsResponse.Announcements, nil
 }
 
-func (s *Session) SendGroupAnnouncement(groupID int64, content string, imageURL string) error {
+func (s *Session) SendGroupAnnouncement(groupID int64, content string, imageURL string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSendGroupAnnouncement, map[string]interface{}{
 		"group_id":  groupID,
 		"content":   content,
 		"image_url": imageURL,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -739,11 +848,11 @@ func (s *Session) SendGroupAnnouncement(groupID int64, content string, imageURL
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) DeleteGroupAnnouncement(groupID int64, announcementID string) error {
+func (s *Session) DeleteGroupAnnouncement(groupID int64, announcementID string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointDeleteGroupAnnouncement, map[string]interface{}{
 		"group_id":        groupID,
 		"announcement_id": announcementID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -751,12 +860,12 @@ func (s *Session) DeleteGroupAnnouncement(groupID int64, announcementID string)
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) GetGroupEssenceMessages(groupID int64, pageIndex int32, pageSize int32) (message []GroupEssenceMessage, isEnd bool, err error) {
+func (s *Session) GetGroupEssenceMessages(groupID int64, pageIndex int32, pageSize int32, options ...RequestOption) (message []GroupEssenceMessage, isEnd bool, err error) {
 	request, err := s.Request("POST", EndpointGetGroupEssenceMessages, map[string]interface{}{
 		"group_id":   groupID,
 		"page_index": pageIndex,
 		"page_size":  pageSize,
-	})
+	}, options...)
 	if err != nil {
 		return nil, false, err
 	}
@@ -771,12 +880,12 @@ func (s *Session) GetGroupEssenceMessages(groupID int64, pageIndex int32, pageSi
 	return essenceMessagesResponse.Messages, essenceMessagesResponse.IsEnd, nil
 }
 
-func (s *Session) SetGroupEssenceMessage(groupID int64, messageSeq int64, isSet bool) error {
+func (s *Session) SetGroupEssenceMessage(groupID int64, messageSeq int64, isSet bool, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSetGroupEssenceMessage, map[string]interface{}{
 		"group_id":    groupID,
 		"message_seq": messageSeq,
 		"is_set":      isSet,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -784,10 +893,10 @@ func (s *Session) SetGroupEssenceMessage(groupID int64, messageSeq int64, isSet
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) QuitGroup(groupID int64) error {
+func (s *Session) QuitGroup(groupID int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointQuitGroup, map[string]interface{}{
 		"group_id": groupID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -795,13 +904,13 @@ func (s *Session) QuitGroup(groupID int64) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SendGroupMessageReaction(groupID int64, messageSeq int64, reaction string, isSet bool) error {
+func (s *Session) SendGroupMessageReaction(groupID int64, messageSeq int64, reaction string, isSet bool, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSendGroupMessageReaction, map[string]interface{}{
 		"group_id":    groupID,
 		"message_seq": messageSeq,
 		"reaction":    reaction,
 		"is_set":      isSet,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -809,11 +918,11 @@ func (s *Session) SendGroupMessageReaction(groupID int64, messageSeq int64, reac
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) SendGroupNudge(groupID int64, userID int64) error {
+func (s *Session) SendGroupNudge(groupID int64, userID int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointSendGroupNudge, map[string]interface{}{
 		"group_id": groupID,
 		"user_id":  userID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -821,12 +930,12 @@ func (s *Session) SendGroupNudge(groupID int64, userID int64) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) GetGroupNotifications(startNotificationSeq int64, isFiltered bool, limit int32) (notifications []interface{}, nextNotificationSeq int64, err error) {
+func (s *Session) GetGroupNotifications(startNotificationSeq int64, isFiltered bool, limit int32, options ...RequestOption) (notifications []GroupNotification, nextNotificationSeq int64, err error) {
 	request, err := s.Request("POST", EndpointGetGroupNotifications, map[string]interface{}{
 		"start_notification_seq": startNotificationSeq,
 		"is_filtered":            isFiltered,
 		"limit":                  limit,
-	})
+	}, options...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -838,7 +947,7 @@ func (s *Session) GetGroupNotifications(startNotificationSeq int64, isFiltered b
 	if err = handleAPIResponse(request, &apiResponse, &notificationsResponse); err != nil {
 		return nil, 0, err
 	}
-	var notifSlice []interface{}
+	var notifSlice []GroupNotification
 	for _, rawNotif := range notificationsResponse.Notifications {
 		notification, err := UnmarshalGroupNotification(rawNotif)
 		if err != nil {
@@ -849,13 +958,13 @@ func (s *Session) GetGroupNotifications(startNotificationSeq int64, isFiltered b
 	return notifSlice, notificationsResponse.NextNotificationSeq, nil
 }
 
-func (s *Session) AcceptGroupRequest(notificationSeq int64, notificationType string, groupID int64, isFiltered bool) error {
+func (s *Session) AcceptGroupRequest(notificationSeq int64, notificationType string, groupID int64, isFiltered bool, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointAcceptGroupRequest, map[string]interface{}{
 		"notification_seq":  notificationSeq,
 		"notification_type": notificationType,
 		"group_id":          groupID,
 		"is_filtered":       isFiltered,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -863,14 +972,14 @@ func (s *Session) AcceptGroupRequest(notificationSeq int64, notificationType str
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) RejectGroupRequest(notificationSeq int64, notificationType string, groupID int64, isFiltered bool, reason string) error {
+func (s *Session) RejectGroupRequest(notificationSeq int64, notificationType string, groupID int64, isFiltered bool, reason string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRejectGroupRequest, map[string]interface{}{
 		"notification_seq":  notificationSeq,
 		"notification_type": notificationType,
 		"group_id":          groupID,
 		"is_filtered":       isFiltered,
 		"reason":            reason,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -878,11 +987,11 @@ func (s *Session) RejectGroupRequest(notificationSeq int64, notificationType str
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) AcceptGroupInvitation(groupID int64, invitationSeq int64) error {
+func (s *Session) AcceptGroupInvitation(groupID int64, invitationSeq int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointAcceptGroupInvitation, map[string]interface{}{
 		"group_id":       groupID,
 		"invitation_seq": invitationSeq,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -890,11 +999,11 @@ func (s *Session) AcceptGroupInvitation(groupID int64, invitationSeq int64) erro
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) RejectGroupInvitation(groupID int64, invitationSeq int64) error {
+func (s *Session) RejectGroupInvitation(groupID int64, invitationSeq int64, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRejectGroupInvitation, map[string]interface{}{
 		"group_id":       groupID,
 		"invitation_seq": invitationSeq,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -902,12 +1011,12 @@ func (s *Session) RejectGroupInvitation(groupID int64, invitationSeq int64) erro
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) UploadPrivateFile(userID int64, fileURI string, fileName string) (string, error) {
+func (s *Session) UploadPrivateFile(userID int64, fileURI string, fileName string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointUploadPrivateFile, map[string]interface{}{
 		"user_id":   userID,
 		"file_uri":  fileURI,
 		"file_name": fileName,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -921,13 +1030,13 @@ func (s *Session) UploadPrivateFile(userID int64, fileURI string, fileName strin
 	return uploadFileResponse.FileID, nil
 }
 
-func (s *Session) UploadGroupFile(groupID int64, fileURI string, fileName string, parentFolderID string) (string, error) {
+func (s *Session) UploadGroupFile(groupID int64, fileURI string, fileName string, parentFolderID string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointUploadGroupFile, map[string]interface{}{
 		"group_id":         groupID,
 		"file_uri":         fileURI,
 		"file_name":        fileName,
 		"parent_folder_id": parentFolderID,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -941,12 +1050,12 @@ func (s *Session) UploadGroupFile(groupID int64, fileURI string, fileName string
 	return uploadFileResponse.FileID, nil
 }
 
-func (s *Session) GetPrivateFileDownloadURL(userID int64, fileID string, fileHash string) (string, error) {
+func (s *Session) GetPrivateFileDownloadURL(userID int64, fileID string, fileHash string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointGetPrivateFileDownloadURL, map[string]interface{}{
 		"user_id":   userID,
 		"file_id":   fileID,
 		"file_hash": fileHash,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -960,11 +1069,11 @@ func (s *Session) GetPrivateFileDownloadURL(userID int64, fileID string, fileHas
 	return downloadURLResponse.DownloadURL, nil
 }
 
-func (s *Session) GetGroupFileDownloadURL(groupID int64, fileID string) (string, error) {
+func (s *Session) GetGroupFileDownloadURL(groupID int64, fileID string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointGetGroupFileDownloadURL, map[string]interface{}{
 		"group_id": groupID,
 		"file_id":  fileID,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -978,11 +1087,11 @@ func (s *Session) GetGroupFileDownloadURL(groupID int64, fileID string) (string,
 	return downloadURLResponse.DownloadURL, nil
 }
 
-func (s *Session) GetGroupFiles(groupID int64, parentFolderID string) ([]GroupFile, []GroupFolder, error) {
+func (s *Session) GetGroupFiles(groupID int64, parentFolderID string, options ...RequestOption) ([]GroupFile, []GroupFolder, error) {
 	request, err := s.Request("POST", EndpointGetGroupFiles, map[string]interface{}{
 		"group_id":         groupID,
 		"parent_folder_id": parentFolderID,
-	})
+	}, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -997,13 +1106,13 @@ func (s *Session) GetGroupFiles(groupID int64, parentFolderID string) ([]GroupFi
 	return groupFilesResponse.Files, groupFilesResponse.Folders, nil
 }
 
-func (s *Session) MoveGroupFile(groupID int64, fileID string, parentFolderID string, targetFolderID string) error {
+func (s *Session) MoveGroupFile(groupID int64, fileID string, parentFolderID string, targetFolderID string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointMoveGroupFile, map[string]interface{}{
 		"group_id":         groupID,
 		"file_id":          fileID,
 		"parent_folder_id": parentFolderID,
 		"target_folder_id": targetFolderID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -1011,13 +1120,13 @@ func (s *Session) MoveGroupFile(groupID int64, fileID string, parentFolderID str
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) RenameGroupFile(groupID int64, fileID string, parentFolderID string, newFileName string) error {
+func (s *Session) RenameGroupFile(groupID int64, fileID string, parentFolderID string, newFileName string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRenameGroupFile, map[string]interface{}{
 		"group_id":         groupID,
 		"file_id":          fileID,
 		"parent_folder_id": parentFolderID,
 		"new_file_name":    newFileName,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -1025,11 +1134,11 @@ func (s *Session) RenameGroupFile(groupID int64, fileID string, parentFolderID s
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) DeleteGroupFile(groupID int64, fileID string) error {
+func (s *Session) DeleteGroupFile(groupID int64, fileID string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointDeleteGroupFile, map[string]interface{}{
 		"group_id": groupID,
 		"file_id":  fileID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -1037,11 +1146,11 @@ func (s *Session) DeleteGroupFile(groupID int64, fileID string) error {
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) CreateGroupFolder(groupID int64, folderName string) (string, error) {
+func (s *Session) CreateGroupFolder(groupID int64, folderName string, options ...RequestOption) (string, error) {
 	request, err := s.Request("POST", EndpointCreateGroupFolder, map[string]interface{}{
 		"group_id":    groupID,
 		"folder_name": folderName,
-	})
+	}, options...)
 	if err != nil {
 		return "", err
 	}
@@ -1055,12 +1164,12 @@ func (s *Session) CreateGroupFolder(groupID int64, folderName string) (string, e
 	return createFolderResponse.FolderID, nil
 }
 
-func (s *Session) RenameGroupFolder(groupID int64, folderID string, newFolderName string) error {
+func (s *Session) RenameGroupFolder(groupID int64, folderID string, newFolderName string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointRenameGroupFolder, map[string]interface{}{
 		"group_id":        groupID,
 		"folder_id":       folderID,
 		"new_folder_name": newFolderName,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -1068,11 +1177,11 @@ func (s *Session) RenameGroupFolder(groupID int64, folderID string, newFolderNam
 	return handleAPIResponse(request, &apiResponse, nil)
 }
 
-func (s *Session) DeleteGroupFolder(groupID int64, folderID string) error {
+func (s *Session) DeleteGroupFolder(groupID int64, folderID string, options ...RequestOption) error {
 	request, err := s.Request("POST", EndpointDeleteGroupFolder, map[string]interface{}{
 		"group_id":  groupID,
 		"folder_id": folderID,
-	})
+	}, options...)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,68 @@
+package Milky_go_sdk
+
+import (
+	"fmt"
+	"time"
+)
+
+// SelfUser is the account a session is logged in to, learned when it opens.
+type SelfUser struct {
+	UIN      int64
+	Nickname string
+	Impl     *ImplInfo // nil when GetImplInfo failed
+}
+
+// Ready is emitted each time Open connects to the websocket gateway. It is
+// dispatched before any event received on the new connection.
+type Ready struct {
+	Self         *SelfUser     // nil when the session could not identify itself
+	Capabilities *Capabilities // nil when the capability handshake failed
+}
+
+// Disconnect is emitted each time the websocket connection is closed, by
+// Close or after a read error.
+type Disconnect struct {
+	Time time.Time
+}
+
+// identify runs the capability handshake and sets Self from GetLoginInfo.
+// The handshake does not depend on the login lookup, Self is only set when
+// the lookup succeeds. The caller must not hold the session lock, it is only
+// taken to store Self.
+func (s *Session) identify() error {
+	s.RLock()
+	gateway := s.RestGateway
+	s.RUnlock()
+
+	caps, capsErr := s.Handshake()
+	if capsErr != nil {
+		s.log().Warnf("capability handshake with %s failed, %s", gateway, capsErr)
+	}
+
+	login, err := s.GetLoginInfo()
+	if err != nil {
+		return fmt.Errorf("verifying credentials with %s: %w", gateway, err)
+	}
+	self := &SelfUser{UIN: login.UIN, Nickname: login.Nickname}
+	if capsErr == nil {
+		self.Impl = &caps.Impl
+	}
+	s.Lock()
+	s.Self = self
+	s.Unlock()
+	s.log().With("self_id", self.UIN).Infof("logged in as %s (%d)", self.Nickname, self.UIN)
+	return nil
+}
+
+// isSelf reports whether userID is the bot. selfID is the self_id of the
+// event frame, the session's own UIN is used when it is 0.
+func (s *Session) isSelf(selfID, userID int64) bool {
+	if selfID == 0 {
+		s.RLock()
+		if s.Self != nil {
+			selfID = s.Self.UIN
+		}
+		s.RUnlock()
+	}
+	return selfID != 0 && userID == selfID
+}
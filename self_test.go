@@ -0,0 +1,119 @@
+package Milky_go_sdk_test
+
+import (
+	"testing"
+	"time"
+
+	milky "github.com/Szzrain/Milky-go-sdk"
+	"github.com/Szzrain/Milky-go-sdk/milkytest"
+)
+
+func TestOpenIdentifiesSelf(t *testing.T) {
+	server := milkytest.NewServer(t)
+	server.Token = "secret"
+	server.Respond(milky.EndpointGetLoginInfo, milky.LoginInfo{UIN: 1001, Nickname: "bot"})
+
+	session := server.NewSession(t)
+	session.Token = "wrong"
+	session.VerifyOnOpen = true
+	if err := session.Open(); err == nil {
+		t.Fatal("Open succeeded with a rejected token")
+	}
+	if server.Connections() != 0 || session.Self != nil {
+		t.Fatalf("connected without valid credentials")
+	}
+
+	session.Token = "secret"
+	ready := make(chan *milky.Ready, 1)
+	session.AddHandler(func(s *milky.Session, r *milky.Ready) { ready <- r })
+	messages := make(chan *milky.ReceiveMessage, 2)
+	session.AddHandler(func(s *milky.Session, m *milky.ReceiveMessage) { messages <- m })
+	if err := session.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-ready:
+		if r.Self == nil || r.Self.UIN != 1001 || r.Self.Nickname != "bot" || r.Self.Impl == nil || r.Capabilities == nil {
+			t.Errorf("unexpected ready event %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ready event")
+	}
+
+	if err := server.WaitForConnections(1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	for _, sender := range []int64{1001, 2002} {
+		if err := server.Push("message_receive", milky.ReceiveMessage{PeerId: 1, SenderId: sender, MessageScene: "group"}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case m := <-messages:
+			if m.FromSelf != (sender == 1001) {
+				t.Errorf("message from %d: FromSelf = %v", sender, m.FromSelf)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+}
+
+func TestOpenHandshakesWhenLoginLookupFails(t *testing.T) {
+	server := milkytest.NewServer(t)
+	server.RespondError(milky.EndpointGetLoginInfo, -1, "login info unavailable")
+
+	session := server.NewSession(t)
+	ready := make(chan *milky.Ready, 1)
+	session.AddHandler(func(s *milky.Session, r *milky.Ready) { ready <- r })
+	if err := session.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-ready:
+		if r.Self != nil || r.Capabilities == nil {
+			t.Errorf("expected capabilities without a self user, got %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ready event")
+	}
+	if session.Self != nil || session.Capabilities() == nil {
+		t.Fatalf("Self = %+v, Capabilities = %+v", session.Self, session.Capabilities())
+	}
+}
+
+func TestOpenDoesNotHoldLockDuringLookup(t *testing.T) {
+	server := milkytest.NewServer(t)
+	server.Respond(milky.EndpointGetLoginInfo, milky.LoginInfo{UIN: 1001, Nickname: "bot"})
+	server.Delay(milky.EndpointGetLoginInfo, 300*time.Millisecond)
+
+	for _, verify := range []bool{true, false} {
+		session := server.NewSession(t)
+		session.VerifyOnOpen = verify
+		ready := make(chan *milky.Ready, 1)
+		session.AddHandler(func(s *milky.Session, r *milky.Ready) { ready <- r })
+		opened := make(chan error, 1)
+		go func() { opened <- session.Open() }()
+
+		time.Sleep(50 * time.Millisecond)
+		start := time.Now()
+		session.RLock()
+		session.RUnlock()
+		if waited := time.Since(start); waited > 100*time.Millisecond {
+			t.Errorf("VerifyOnOpen=%v: readers waited %s for the login lookup", verify, waited)
+		}
+
+		if err := <-opened; err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case r := <-ready:
+			if r.Self == nil || r.Self.UIN != 1001 {
+				t.Errorf("VerifyOnOpen=%v: unexpected ready event %+v", verify, r)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("no ready event")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SlogLogger adapts a log/slog handler to Logger. Fields attached by the
+// SDK, such as endpoint, event_type, group_id and attempt, become slog
+// attributes.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to h.
+func NewSlogLogger(h slog.Handler) *SlogLogger {
+	return &SlogLogger{logger: slog.New(h)}
+}
+
+// LogLevelForSlog returns the Session.LogLevel matching a slog level, so the
+// SDK does not format messages the handler would drop anyway.
+func LogLevelForSlog(level slog.Level) int {
+	switch {
+	case level <= slog.LevelDebug:
+		return LogDebug
+	case level <= slog.LevelInfo:
+		return LogInformational
+	case level <= slog.LevelWarn:
+		return LogWarning
+	default:
+		return LogError
+	}
+}
+
+// WithFields implements FieldLogger.
+func (l *SlogLogger) WithFields(keyvals ...interface{}) Logger {
+	return &SlogLogger{logger: l.logger.With(keyvals...)}
+}
+
+func (l *SlogLogger) log(level slog.Level, msg string) {
+	l.logger.Log(context.Background(), level, msg)
+}
+
+func (l *SlogLogger) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *SlogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *SlogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *SlogLogger) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *SlogLogger) Info(args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(args...))
+}
+
+func (l *SlogLogger) Error(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+}
+
+func (l *SlogLogger) Debug(args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprint(args...))
+}
+
+func (l *SlogLogger) Warn(args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(args...))
+}
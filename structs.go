@@ -17,19 +17,78 @@ type Session struct {
 	// stores sessions current rest gateway
 	RestGateway string
 
+	// The account the session is logged in to, set by Open from
+	// GetLoginInfo. nil until then.
+	Self *SelfUser
+
+	// Make Open fail when GetLoginInfo fails, such as when the token is
+	// rejected, rather than connecting without knowing Self. Otherwise the
+	// lookup is made after connecting, before Ready is dispatched.
+	VerifyOnOpen bool
+
+	// Check the bot's group role before admin operations such as
+	// KickGroupMember, failing with ErrInsufficientPermission instead of
+	// sending requests bound to fail. Roles are cached for RoleCacheTTL,
+	// DefaultRoleCacheTTL when 0.
+	CheckPermissions bool
+	RoleCacheTTL     time.Duration
+	roles            roleCache
+
 	apiEndpoints *apiEndpoints
+	capabilities *Capabilities
+	gatewayMu    sync.RWMutex // guards apiEndpoints and capabilities
 
+	// Messages above this level are not passed to Logger, see LogError to
+	// LogDebug. New sets it to LogDebug.
 	LogLevel int
 
+	// Extra header, query parameter and JSON field names whose values are
+	// masked in log output, on top of DefaultSensitiveFields. The Token is
+	// always masked.
+	SensitiveFields []string
+
+	// Send the token to the websocket gateway in an Authorization header
+	// rather than the access_token query parameter.
+	WSTokenInHeader bool
+
 	// Should the session reconnect the websocket on errors.
 	ShouldReconnectOnError bool
 
+	// Where the session logs to, nil discards all output. Use NewSlogLogger
+	// to log through log/slog.
 	Logger Logger
 
 	// Whether or not to call event handlers synchronously.
 	// e.g. false = launch event handlers in their own goroutines.
 	SyncEvents bool
 
+	// When set, event handlers run on the pool in per conversation order and
+	// SyncEvents is ignored.
+	WorkerPool *WorkerPool
+
+	// Called with panics recovered from event handlers. When nil they are
+	// logged with their stack.
+	OnHandlerPanic func(s *Session, p *HandlerPanic)
+
+	// Event handlers running longer than this are reported to OnSlowHandler.
+	// The context given to ContextHandler handlers expires at the same time.
+	// 0 means no timeout. See HandlerWithTimeout to set it per handler.
+	HandlerTimeout time.Duration
+
+	// Called once for each handler still running after its timeout. When nil
+	// slow handlers are logged.
+	OnSlowHandler func(s *Session, eventType string, event interface{}, timeout time.Duration)
+
+	// When set, every raw event frame received is passed to the recorder.
+	Recorder FrameRecorder
+
+	// Receives REST, event and connection measurements, see
+	// NewPrometheusMetrics. nil discards them.
+	Metrics Metrics
+
+	// Starts spans around REST calls and event handlers. nil traces nothing.
+	Tracer Tracer
+
 	// Exposed but should not be modified by User.
 
 	// Max number of REST API retries
@@ -55,6 +114,9 @@ type Session struct {
 	handlers     map[string][]*eventHandlerInstance
 	onceHandlers map[string][]*eventHandlerInstance
 
+	// number of event handlers waiting or running, reported to Metrics
+	pendingHandlers int64
+
 	// The websocket connection.
 	wsConn *websocket.Conn
 
@@ -66,6 +128,10 @@ type Session struct {
 
 	// used to make sure gateway websocket writes do not happen concurrently
 	wsMutex sync.Mutex
+
+	// wakes the reconnect loop from its backoff, nil when it is not running
+	reconnectWake chan struct{}
+	reconnectMu   sync.Mutex
 }
 
 type APIErrorMessage struct {
@@ -0,0 +1,84 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxMuteDuration is the longest mute the platform accepts.
+const MaxMuteDuration = 30 * 24 * time.Hour
+
+// ErrMuteDuration is returned, without sending the request, for mutes that
+// are negative or longer than MaxMuteDuration.
+var ErrMuteDuration = errors.New("mute duration out of range")
+
+// unixTime converts Unix seconds to a time.Time, 0 is the zero Time.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// seconds converts a non-negative duration to the whole seconds the protocol
+// expects, rounding up so short durations are not sent as 0.
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// validMuteDuration checks a mute duration.
+func validMuteDuration(d time.Duration) error {
+	if d < 0 || d > MaxMuteDuration {
+		return fmt.Errorf("%w: %s, maximum is %s", ErrMuteDuration, d, MaxMuteDuration)
+	}
+	return nil
+}
+
+// Timestamp returns the time the event was generated.
+func (e *Event) Timestamp() time.Time { return unixTime(e.Time) }
+
+// SentAt returns the time the message was sent.
+func (m *ReceiveMessage) SentAt() time.Time { return unixTime(m.Time) }
+
+// SentAt returns the time the message was sent.
+func (m *MessageRet) SentAt() time.Time { return unixTime(m.Time) }
+
+// JoinedAt returns the time the member joined the group.
+func (m *GroupMemberInfo) JoinedAt() time.Time { return unixTime(m.JoinTime) }
+
+// LastSentAt returns the time the member last spoke, or the zero Time.
+func (m *GroupMemberInfo) LastSentAt() time.Time { return unixTime(m.LastSentTime) }
+
+// PublishedAt returns the time the announcement was published.
+func (a *GroupAnnouncement) PublishedAt() time.Time { return unixTime(a.Time) }
+
+// SentAt returns the time the essence message was sent.
+func (m *GroupEssenceMessage) SentAt() time.Time { return unixTime(m.MessageTime) }
+
+// OperatedAt returns the time the message was set as essence.
+func (m *GroupEssenceMessage) OperatedAt() time.Time { return unixTime(m.OperationTime) }
+
+// UploadedAt returns the time the file was uploaded.
+func (f *GroupFile) UploadedAt() time.Time { return unixTime(f.UploadedTime) }
+
+// ExpiresAt returns the time the file expires, or the zero Time for files
+// that do not expire.
+func (f *GroupFile) ExpiresAt() time.Time { return unixTime(f.ExpireTime) }
+
+// CreatedAt returns the time the folder was created.
+func (f *GroupFolder) CreatedAt() time.Time { return unixTime(f.CreatedTime) }
+
+// LastModifiedAt returns the time the folder was last modified.
+func (f *GroupFolder) LastModifiedAt() time.Time { return unixTime(f.LastModifiedTime) }
+
+// RequestedAt returns the time the request was made.
+func (r *GroupRequest) RequestedAt() time.Time { return unixTime(r.Time) }
+
+// MuteDuration returns how long the member was muted, 0 when unmuted.
+func (m *GroupMute) MuteDuration() time.Duration {
+	return time.Duration(m.Duration) * time.Second
+}
+
+// IsUnmute reports whether the event lifts a mute.
+func (m *GroupMute) IsUnmute() bool { return m.Duration == 0 }
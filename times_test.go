@@ -0,0 +1,55 @@
+package Milky_go_sdk
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeAccessors(t *testing.T) {
+	m := &ReceiveMessage{Time: 1700000000}
+	if !m.SentAt().Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("SentAt = %v", m.SentAt())
+	}
+	f := &GroupFile{UploadedTime: 1700000000}
+	if !f.ExpiresAt().IsZero() || f.UploadedAt().Unix() != 1700000000 {
+		t.Errorf("unexpected file times %v, %v", f.UploadedAt(), f.ExpiresAt())
+	}
+	mute := &GroupMute{Duration: 600}
+	if mute.MuteDuration() != 10*time.Minute || mute.IsUnmute() {
+		t.Errorf("unexpected mute duration %v", mute.MuteDuration())
+	}
+}
+
+func TestSetGroupMemberMuteDuration(t *testing.T) {
+	var payloads []string
+	s, _ := New("", "http://milky", "", nil)
+	s.Client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		payloads = append(payloads, string(b))
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"status":"ok","retcode":0,"data":{}}`)), Header: http.Header{}}, nil
+	})}
+
+	if err := s.SetGroupMemberMuteDuration(1, 2, 90*time.Second+time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 || payloads[0] != `{"duration":91,"group_id":1,"user_id":2}` {
+		t.Errorf("unexpected payloads %q", payloads)
+	}
+
+	for _, err := range []error{
+		s.SetGroupMemberMuteDuration(1, 2, MaxMuteDuration+time.Second),
+		s.SetGroupMemberMuteDuration(1, 2, -time.Second),
+		s.SetGroupMemberMute(1, 2, int32(MaxMuteDuration/time.Second)+1),
+	} {
+		if !errors.Is(err, ErrMuteDuration) {
+			t.Errorf("expected ErrMuteDuration, got %v", err)
+		}
+	}
+	if len(payloads) != 1 {
+		t.Errorf("out of range mutes were sent: %q", payloads)
+	}
+}
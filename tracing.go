@@ -0,0 +1,93 @@
+package Milky_go_sdk
+
+import (
+	"context"
+)
+
+// Tracer starts spans around REST calls and event handler invocations. An
+// adapter for a tracing library only needs to implement Tracer and Span.
+type Tracer interface {
+	// Start starts a span as a child of any span in ctx and returns a
+	// context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttribute(key string, value interface{})
+
+	// End finishes the span, err is nil if the operation succeeded.
+	End(err error)
+}
+
+// NopTracer creates spans that record nothing. It is the default.
+type NopTracer struct{}
+
+// Start implements Tracer.
+func (NopTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(string, interface{}) {}
+func (nopSpan) End(error)                        {}
+
+// tracer returns the Tracer to use. It is never nil.
+func (s *Session) tracer() Tracer {
+	if s.Tracer == nil {
+		return NopTracer{}
+	}
+	return s.Tracer
+}
+
+// instrumented reports whether a Tracer or Metrics other than the no-op ones
+// is set, so measurements that cost extra work are worth taking.
+func (s *Session) instrumented() bool {
+	_, nopTracer := s.tracer().(NopTracer)
+	_, nopMetrics := s.metrics().(NopMetrics)
+	return !nopTracer || !nopMetrics
+}
+
+// ContextEventHandler is an EventHandler that receives the context of the
+// span wrapping its invocation.
+type ContextEventHandler interface {
+	EventHandler
+	HandleContext(ctx context.Context, s *Session, i interface{})
+}
+
+// ContextHandler returns a handler for events of type T that receives the
+// handler span's context, for use with AddHandler:
+//
+//	s.AddHandler(ContextHandler(func(ctx context.Context, s *Session, m *ReceiveMessage) {
+//		_, _ = s.SendGroupMessage(m.PeerId, &reply, WithContext(ctx))
+//	}))
+//
+// It returns nil if T is not an event type.
+func ContextHandler[T any](fn func(ctx context.Context, s *Session, e *T)) EventHandler {
+	for t, p := range eventProviders() {
+		if _, ok := p.New().(*T); ok {
+			return contextEventHandler[T]{eventType: t, fn: fn}
+		}
+	}
+	return nil
+}
+
+type contextEventHandler[T any] struct {
+	eventType string
+	fn        func(context.Context, *Session, *T)
+}
+
+func (eh contextEventHandler[T]) Type() string {
+	return eh.eventType
+}
+
+func (eh contextEventHandler[T]) Handle(s *Session, i interface{}) {
+	eh.HandleContext(context.Background(), s, i)
+}
+
+func (eh contextEventHandler[T]) HandleContext(ctx context.Context, s *Session, i interface{}) {
+	if t, ok := i.(*T); ok {
+		eh.fn(ctx, s, t)
+	}
+}
@@ -0,0 +1,155 @@
+package Milky_go_sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type traceKey struct{}
+
+type testSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (sp *testSpan) SetAttribute(key string, value interface{}) { sp.attrs[key] = value }
+func (sp *testSpan) End(err error)                              { sp.err, sp.ended = err, true }
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(traceKey{}).(string)
+	sp := &testSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	t.mu.Lock()
+	t.spans = append(t.spans, sp)
+	t.mu.Unlock()
+	return context.WithValue(ctx, traceKey{}, name), sp
+}
+
+func TestTracer(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{"uin":1,"nickname":"bot"}}`))
+	}))
+	defer srv.Close()
+
+	tracer := &testTracer{}
+	s, _ := New("", srv.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.Tracer = tracer
+	s.SyncEvents = true
+	s.Client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		parent, _ := r.Context().Value(traceKey{}).(string)
+		seen = append(seen, parent)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+
+	s.AddHandler(ContextHandler(func(ctx context.Context, s *Session, n *GroupNudge) {
+		if _, err := s.GetLoginInfo(WithContext(ctx)); err != nil {
+			t.Error(err)
+		}
+	}))
+	if _, err := s.dispatchFrame([]byte(`{"event_type":"group_nudge","data":{"group_id":1,"sender_id":2,"receiver_id":3}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("got %d spans", len(tracer.spans))
+	}
+	handler, rest := tracer.spans[0], tracer.spans[1]
+	if handler.name != "milky.handler group_nudge" || !handler.ended || handler.attrs["milky.event_type"] != "group_nudge" {
+		t.Errorf("unexpected handler span %+v", handler)
+	}
+	if rest.name != "milky.rest get_login_info" || rest.parent != handler.name || !rest.ended || rest.err != nil {
+		t.Errorf("unexpected rest span %+v", rest)
+	}
+	if rest.attrs["http.status_code"] != http.StatusOK || rest.attrs["milky.retcode"] != 0 {
+		t.Errorf("unexpected rest span attributes %v", rest.attrs)
+	}
+	if len(seen) != 1 || seen[0] != rest.name {
+		t.Errorf("request context carried span %v", seen)
+	}
+}
+
+func TestTracerRetCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"failed","retcode":-403,"message":"not allowed"}`))
+	}))
+	defer srv.Close()
+
+	tracer := &testTracer{}
+	s, _ := New("", srv.URL, "", &TestLogger{})
+	s.Tracer = tracer
+
+	_, err := s.GetLoginInfo(WithHeader("X-Test", "1"), WithContext(context.WithValue(context.Background(), traceKey{}, "caller")))
+	if err == nil {
+		t.Fatal("expected the API error")
+	}
+	if len(tracer.spans) != 1 {
+		t.Fatalf("got %d spans", len(tracer.spans))
+	}
+	rest := tracer.spans[0]
+	if rest.parent != "caller" || rest.err == nil || rest.err.Error() != err.Error() || rest.attrs["milky.retcode"] != -403 {
+		t.Errorf("unexpected rest span %+v", rest)
+	}
+}
+
+func TestTracerRequestOptions(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body)+" "+r.Header.Get("X-Caller"))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","retcode":0,"data":{}}`))
+	}))
+	defer srv.Close()
+
+	tracer := &testTracer{}
+	s, _ := New("", srv.URL, "", &TestLogger{})
+	s.LogLevel = LogError
+	s.Tracer = tracer
+
+	// A caller defined option may rely on the request being built, and runs
+	// once per call, not once per attempt.
+	calls := 0
+	option := func(cfg *RequestConfig) {
+		calls++
+		cfg.Request.Header.Set("X-Caller", "yes")
+		cfg.Request = cfg.Request.WithContext(context.WithValue(cfg.Request.Context(), traceKey{}, "caller"))
+	}
+	if _, err := s.GetLoginInfo(option); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("option ran %d times", calls)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != "{} yes" {
+		t.Errorf("attempts sent %q", bodies)
+	}
+	if len(tracer.spans) != 1 || tracer.spans[0].parent != "caller" || tracer.spans[0].attrs["milky.retries"] != 1 {
+		t.Errorf("unexpected spans %+v", tracer.spans)
+	}
+}
+
+func TestContextHandlerUnknownType(t *testing.T) {
+	if h := ContextHandler(func(ctx context.Context, s *Session, e *struct{}) {}); h != nil {
+		t.Errorf("expected nil handler, got %T", h)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,239 @@
+package Milky_go_sdk
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what a WorkerPool does with an event when the queue
+// of its conversation is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue, holding up the websocket
+	// read loop.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the incoming event.
+	OverflowDropNewest
+)
+
+// WorkerPool runs event handlers on a fixed number of workers. Events with
+// the same conversation key always go to the same worker, so events from one
+// chat are handled in order while different chats run in parallel. Assign it
+// to Session.WorkerPool; one pool can serve several sessions.
+type WorkerPool struct {
+	policy OverflowPolicy
+	queues []chan poolJob
+	locks  []sync.Mutex
+
+	// Called with the conversation key of every dropped event.
+	OnDrop func(key string)
+
+	mu      sync.RWMutex
+	closed  bool
+	done    chan struct{}  // closed by Close to release blocked producers
+	senders sync.WaitGroup // producers that may still send to a queue
+	wg      sync.WaitGroup
+	next    uint32 // round robin for events without a conversation
+	pending int64
+}
+
+// poolJob is a queued event handler with the conversation key it was
+// submitted under.
+type poolJob struct {
+	key string
+	run func()
+}
+
+// NewWorkerPool starts workers goroutines, each with a queue of queueSize
+// events. Non-positive values are taken as 1.
+func NewWorkerPool(workers, queueSize int, policy OverflowPolicy) *WorkerPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	p := &WorkerPool{
+		policy: policy,
+		queues: make([]chan poolJob, workers),
+		locks:  make([]sync.Mutex, workers),
+		done:   make(chan struct{}),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan poolJob, queueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+func (p *WorkerPool) work(queue chan poolJob) {
+	defer p.wg.Done()
+	for job := range queue {
+		job.run()
+		atomic.AddInt64(&p.pending, -1)
+	}
+}
+
+// Submit queues job on the worker for key, an empty key picks any worker. It
+// returns false if job was dropped to apply the overflow policy or the pool
+// is closed. OnDrop is called with the key of whichever event was dropped.
+//
+// With OverflowBlock a handler must not submit to its own conversation: the
+// worker cannot drain the queue it is waiting on until the pool is closed.
+func (p *WorkerPool) Submit(key string, run func()) bool {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return false
+	}
+	p.senders.Add(1)
+	p.mu.RUnlock()
+	defer p.senders.Done()
+
+	job := poolJob{key: key, run: run}
+	i := p.worker(key)
+	queue := p.queues[i]
+	if p.policy == OverflowBlock {
+		select {
+		case queue <- job:
+			p.enqueued()
+			return true
+		case <-p.done:
+			return false
+		}
+	}
+
+	// Producers are serialised per queue, so after taking one event out
+	// there is room for the new one.
+	p.locks[i].Lock()
+	defer p.locks[i].Unlock()
+	select {
+	case queue <- job:
+		p.enqueued()
+		return true
+	default:
+	}
+	if p.policy == OverflowDropNewest {
+		p.dropped(key)
+		return false
+	}
+	select {
+	case oldest := <-queue:
+		atomic.AddInt64(&p.pending, -1)
+		p.dropped(oldest.key)
+	default:
+	}
+	queue <- job
+	p.enqueued()
+	return true
+}
+
+// enqueued counts a job once it is in a queue. The worker may already have
+// finished it, so the count can dip below zero for a moment.
+func (p *WorkerPool) enqueued() {
+	atomic.AddInt64(&p.pending, 1)
+}
+
+// Len returns the number of events queued or being handled.
+func (p *WorkerPool) Len() int {
+	if n := atomic.LoadInt64(&p.pending); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// Close stops accepting events, releases producers blocked on a full queue
+// and waits for the queued events to be handled.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	p.senders.Wait()
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+func (p *WorkerPool) worker(key string) int {
+	if key == "" {
+		return int(atomic.AddUint32(&p.next, 1) % uint32(len(p.queues)))
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *WorkerPool) dropped(key string) {
+	if p.OnDrop != nil {
+		p.OnDrop(key)
+	}
+}
+
+// ConversationKey returns the key WorkerPool orders an event by: the group
+// for group events, the friend for private events, or "" for events that do
+// not belong to a conversation.
+func ConversationKey(event interface{}) string {
+	switch e := event.(type) {
+	case *ReceiveMessage:
+		return string(e.MessageScene) + ":" + strconv.FormatInt(e.PeerId, 10)
+	case *MessageRecall:
+		return string(e.MessageScene) + ":" + strconv.FormatInt(e.PeerID, 10)
+	case *FriendRequest:
+		return friendKey(e.InitiatorID)
+	case *FriendNudge:
+		return friendKey(e.UserID)
+	case *FriendFileUpload:
+		return friendKey(e.UserID)
+	case *GroupNudge:
+		return groupKey(e.GroupID)
+	case *GroupMessageReaction:
+		return groupKey(e.GroupID)
+	case *GroupAdminChange:
+		return groupKey(e.GroupID)
+	case *GroupEssenceMessageChange:
+		return groupKey(e.GroupID)
+	case *GroupNameChange:
+		return groupKey(e.GroupID)
+	case *GroupFileUpload:
+		return groupKey(e.GroupID)
+	case *GroupMute:
+		return groupKey(e.GroupID)
+	case *GroupWholeMute:
+		return groupKey(e.GroupID)
+	case *GroupMemberIncrease:
+		return groupKey(e.GroupID)
+	case *GroupMemberDecrease:
+		return groupKey(e.GroupID)
+	case *GroupJoinRequest:
+		return groupKey(e.GroupID)
+	case *GroupInvitedJoinRequest:
+		return groupKey(e.GroupID)
+	case *GroupInvitation:
+		return groupKey(e.GroupID)
+	default:
+		return ""
+	}
+}
+
+func groupKey(groupID int64) string {
+	return "group:" + strconv.FormatInt(groupID, 10)
+}
+
+func friendKey(userID int64) string {
+	return "friend:" + strconv.FormatInt(userID, 10)
+}
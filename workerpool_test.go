@@ -0,0 +1,173 @@
+package Milky_go_sdk
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolOrdering(t *testing.T) {
+	pool := NewWorkerPool(4, 64, OverflowBlock)
+	s, _ := New("", "", "", &TestLogger{})
+	s.WorkerPool = pool
+
+	var mu sync.Mutex
+	seqs := map[int64][]int64{}
+	s.AddHandler(func(s *Session, m *ReceiveMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		seqs[m.PeerId] = append(seqs[m.PeerId], m.MessageSeq)
+	})
+
+	for seq := 1; seq <= 50; seq++ {
+		for group := 1; group <= 3; group++ {
+			frame := fmt.Sprintf(`{"event_type":"message_receive","data":{"peer_id":%d,"message_seq":%d,"sender_id":2,"time":1,"message_scene":"group","segments":[]}}`, group, seq)
+			if _, err := s.dispatchFrame([]byte(frame)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	pool.Close()
+
+	for group := int64(1); group <= 3; group++ {
+		got := seqs[group]
+		if len(got) != 50 {
+			t.Fatalf("group %d: handled %d events", group, len(got))
+		}
+		for i, seq := range got {
+			if seq != int64(i+1) {
+				t.Fatalf("group %d: out of order %v", group, got)
+			}
+		}
+	}
+}
+
+func TestWorkerPoolParallel(t *testing.T) {
+	pool := NewWorkerPool(2, 1, OverflowBlock)
+	defer pool.Close()
+
+	// Find two keys handled by different workers.
+	a, b := "group:1", ""
+	for i := 2; b == ""; i++ {
+		if k := groupKey(int64(i)); pool.worker(k) != pool.worker(a) {
+			b = k
+		}
+	}
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	pool.Submit(a, func() { <-release })
+	pool.Submit(b, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a blocked conversation held up another")
+	}
+	close(release)
+}
+
+func TestWorkerPoolOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		policy  OverflowPolicy
+		want    []int
+		wantOK  []bool
+		dropped string
+	}{
+		{OverflowDropNewest, []int{0, 1}, []bool{true, false}, "k2"},
+		{OverflowDropOldest, []int{0, 2}, []bool{true, true}, "k1"},
+	} {
+		pool := NewWorkerPool(1, 1, tc.policy)
+		var drops []string
+		pool.OnDrop = func(key string) { drops = append(drops, key) }
+
+		var mu sync.Mutex
+		var ran []int
+		started := make(chan struct{})
+		release := make(chan struct{})
+		pool.Submit("k", func() {
+			close(started)
+			<-release
+			mu.Lock()
+			ran = append(ran, 0)
+			mu.Unlock()
+		})
+		<-started
+		// Keys k1 and k2 are distinct but share the single worker.
+		for i := 1; i <= 2; i++ {
+			i := i
+			ok := pool.Submit(fmt.Sprintf("k%d", i), func() {
+				mu.Lock()
+				ran = append(ran, i)
+				mu.Unlock()
+			})
+			if ok != tc.wantOK[i-1] {
+				t.Errorf("policy %d: submit %d returned %v", tc.policy, i, ok)
+			}
+		}
+		if n := pool.Len(); n != 2 {
+			t.Errorf("policy %d: Len %d, want the running and the queued event", tc.policy, n)
+		}
+		close(release)
+		pool.Close()
+
+		if fmt.Sprint(ran) != fmt.Sprint(tc.want) || len(drops) != 1 || drops[0] != tc.dropped {
+			t.Errorf("policy %d: ran %v with drops %v, want %v and %s dropped", tc.policy, ran, drops, tc.want, tc.dropped)
+		}
+		if pool.Submit("k", func() {}) {
+			t.Errorf("policy %d: submit after close succeeded", tc.policy)
+		}
+	}
+}
+
+func TestWorkerPoolCloseReleasesBlockedSubmit(t *testing.T) {
+	pool := NewWorkerPool(1, 1, OverflowBlock)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit("k", func() {
+		close(started)
+		<-release
+	})
+	<-started
+	pool.Submit("k", func() {})
+
+	blocked := make(chan bool)
+	go func() { blocked <- pool.Submit("k", func() {}) }()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+	select {
+	case ok := <-blocked:
+		if ok {
+			t.Error("blocked submit succeeded after close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close did not release the blocked submit")
+	}
+	if pool.Len() != 2 {
+		t.Errorf("Len %d, want 2", pool.Len())
+	}
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close did not return")
+	}
+}
+
+func TestConversationKey(t *testing.T) {
+	for event, want := range map[interface{}]string{
+		&ReceiveMessage{MessageScene: "friend", PeerId: 5}: "friend:5",
+		&GroupMute{GroupID: 7}:                             "group:7",
+		&FriendNudge{UserID: 9}:                            "friend:9",
+		&BotOffline{}:                                      "",
+	} {
+		if got := ConversationKey(event); got != want {
+			t.Errorf("ConversationKey(%T) = %q, want %q", event, got, want)
+		}
+	}
+}
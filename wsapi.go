@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -37,10 +38,27 @@ type resumePacket struct {
 
 // Open creates a websocket connection
 func (s *Session) Open() error {
-	s.Logger.Debugf("called")
+	s.log().Debugf("called")
 
 	var err error
 
+	// With VerifyOnOpen, learn who the bot is before connecting. The round
+	// trip is made without holding the session lock, so event handlers and
+	// other readers are not held up by a slow REST gateway.
+	s.RLock()
+	open, identify := s.wsConn != nil, s.RestGateway != ""
+	s.RUnlock()
+	if open {
+		return ErrWSAlreadyOpen
+	}
+	if identify && s.VerifyOnOpen {
+		if err = s.identify(); err != nil {
+			s.log().Errorf("%s", err)
+			return err
+		}
+		identify = false
+	}
+
 	// Prevent Open or other major Session functions from
 	// being called while Open is still running.
 	s.Lock()
@@ -57,15 +75,19 @@ func (s *Session) Open() error {
 	}
 
 	// Connect to the WSGateway
-	s.Logger.Debugf("connecting to gateway %s", s.WSGateway)
+	s.log().Debugf("connecting to gateway %s", s.WSGateway)
 	header := http.Header{}
 	addr := s.WSGateway
 	if s.Token != "" {
-		addr = s.WSGateway + "?access_token=" + s.Token
+		if s.WSTokenInHeader {
+			header.Set("Authorization", "Bearer "+s.Token)
+		} else {
+			addr = s.WSGateway + "?access_token=" + url.QueryEscape(s.Token)
+		}
 	}
 	s.wsConn, _, err = s.Dialer.Dial(addr, header)
 	if err != nil {
-		s.Logger.Errorf("error connecting to gateway %s, %s", s.WSGateway, err)
+		s.log().Errorf("error connecting to gateway %s, %s", s.WSGateway, err)
 		s.wsConn = nil // Just to be safe.
 		return err
 	}
@@ -88,9 +110,22 @@ func (s *Session) Open() error {
 
 	// Start sending heartbeats and reading messages
 	go s.heartbeat(s.wsConn, s.listening, h.HeartbeatInterval)
-	go s.listen(s.wsConn, s.listening)
+	go func(wsConn *websocket.Conn, listening <-chan interface{}) {
+		// Otherwise learn who the bot is and what the implementation
+		// supports here, before any event is received.
+		if identify {
+			if err := s.identify(); err != nil {
+				s.log().Warnf("%s", err)
+			}
+		}
+		s.RLock()
+		ready := &Ready{Self: s.Self, Capabilities: s.Capabilities()}
+		s.RUnlock()
+		s.handleEvent(readyEventType, ready)
+		s.listen(wsConn, listening)
+	}(s.wsConn, s.listening)
 
-	s.Logger.Debug("exiting")
+	s.log().Debug("exiting")
 	return nil
 }
 
@@ -98,7 +133,7 @@ func (s *Session) Open() error {
 // listening channel is closed, or an error occurs.
 func (s *Session) listen(wsConn *websocket.Conn, listening <-chan interface{}) {
 
-	s.Logger.Debug("called")
+	s.log().Debug("called")
 
 	for {
 
@@ -115,15 +150,15 @@ func (s *Session) listen(wsConn *websocket.Conn, listening <-chan interface{}) {
 
 			if sameConnection {
 
-				s.Logger.Warnf("error reading from gateway %s websocket, %s", s.WSGateway, err)
+				s.log().Warnf("error reading from gateway %s websocket, %s", s.WSGateway, err)
 				// There has been an error reading, close the websocket so that
 				// OnDisconnect event is emitted.
 				err := s.Close()
 				if err != nil {
-					s.Logger.Warnf("error closing session connection, %s", err)
+					s.log().Warnf("error closing session connection, %s", err)
 				}
 
-				s.Logger.Infof("calling reconnect() now")
+				s.log().Infof("calling reconnect() now")
 				s.reconnect()
 			}
 
@@ -165,7 +200,7 @@ func (s *Session) HeartbeatLatency() time.Duration {
 // is still connected.
 func (s *Session) heartbeat(wsConn *websocket.Conn, listening <-chan interface{}, heartbeatIntervalMsec time.Duration) {
 
-	s.Logger.Debug("called")
+	s.log().Debug("called")
 
 	if listening == nil || wsConn == nil {
 		return
@@ -180,16 +215,16 @@ func (s *Session) heartbeat(wsConn *websocket.Conn, listening <-chan interface{}
 		last := s.LastHeartbeatAck
 		s.RUnlock()
 		sequence := 0
-		s.Logger.Debugf("sending gateway websocket heartbeat seq %d", sequence)
+		s.log().Debugf("sending gateway websocket heartbeat seq %d", sequence)
 		s.wsMutex.Lock()
 		s.LastHeartbeatSent = time.Now().UTC()
 		err = wsConn.WriteJSON(heartbeatOp{1, int64(sequence)})
 		s.wsMutex.Unlock()
 		if err != nil || time.Now().UTC().Sub(last) > (heartbeatIntervalMsec*FailedHeartbeatAcks) {
 			if err != nil {
-				s.Logger.Debugf("error sending heartbeat to gateway %s, %s", s.WSGateway, err)
+				s.log().Debugf("error sending heartbeat to gateway %s, %s", s.WSGateway, err)
 			} else {
-				s.Logger.Errorf("haven't gotten a heartbeat ACK in %v, triggering a reconnection", time.Now().UTC().Sub(last))
+				s.log().Errorf("haven't gotten a heartbeat ACK in %v, triggering a reconnection", time.Now().UTC().Sub(last))
 			}
 			s.Close()
 			s.reconnect()
@@ -226,14 +261,14 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 
 		z, err2 := zlib.NewReader(reader)
 		if err2 != nil {
-			s.Logger.Errorf("error uncompressing websocket message, %s", err)
+			s.log().Errorf("error uncompressing websocket message, %s", err)
 			return nil, err2
 		}
 
 		defer func() {
 			err3 := z.Close()
 			if err3 != nil {
-				s.Logger.Warnf("error closing zlib, %s", err)
+				s.log().Warnf("error closing zlib, %s", err)
 			}
 		}()
 
@@ -243,11 +278,24 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 	// read earlier data from the reader
 	var rawData []byte
 	if rawData, err = io.ReadAll(reader); err != nil {
-		s.Logger.Errorf("error reading websocket message, %s", err)
+		s.log().Errorf("error reading websocket message, %s", err)
 		return nil, err
 	}
 	// print in debug mode
-	s.Logger.Debugf("received websocket message: %s", string(rawData))
+	s.log().Debugf("received websocket message: %s", string(rawData))
+
+	if s.Recorder != nil {
+		s.Recorder.RecordFrame(time.Now(), rawData)
+	}
+
+	return s.dispatchFrame(rawData)
+}
+
+// dispatchFrame decodes an uncompressed event frame and passes it to the
+// registered handlers.
+func (s *Session) dispatchFrame(rawData []byte) (*Event, error) {
+	var err error
+	start := time.Now()
 
 	// Create a new buffer to hold the raw data.
 	var rawDataBuffer bytes.Buffer
@@ -257,9 +305,10 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 	var e *Event
 	decoder := json.NewDecoder(&rawDataBuffer)
 	if err = decoder.Decode(&e); err != nil {
-		s.Logger.Errorf("error decoding websocket message, %s", err)
+		s.log().Errorf("error decoding websocket message, %s", err)
 		return e, err
 	}
+	log := s.log().With(eventLogFields(e)...)
 
 	// Map event to registered event handlers and pass it along to any registered handlers.
 	if eh, ok := registeredInterfaceProviders[e.Type]; ok {
@@ -267,21 +316,49 @@ func (s *Session) onEvent(messageType int, message []byte) (*Event, error) {
 
 		// Attempt to unmarshal our event.
 		if err = json.Unmarshal(e.RawData, e.Struct); err != nil {
-			s.Logger.Errorf("error unmarshalling %s event, %s", e.Type, err)
+			log.Errorf("error unmarshalling %s event, %s", e.Type, err)
 		}
+		s.metrics().ObserveEvent(e.Type, time.Since(start))
 
+		if m, ok := e.Struct.(*ReceiveMessage); ok {
+			m.FromSelf = s.isSelf(e.SelfID, m.SenderId)
+		}
+		if s.CheckPermissions {
+			s.observeRoles(e.Struct)
+		}
 		s.handleEvent(e.Type, e.Struct)
 	} else {
-		s.Logger.Warnf("unknown event: Type: %s, Data: %s", e.Type, string(e.RawData))
+		s.metrics().ObserveEvent(e.Type, time.Since(start))
+		log.Warnf("unknown event: Type: %s, Data: %s", e.Type, string(e.RawData))
 	}
 	// s.handleEvent(eventEventType, e)
 
 	return e, nil
 }
 
+// eventLogFields returns the log fields identifying an event: its type and,
+// for group events, the group ID.
+func eventLogFields(e *Event) []interface{} {
+	fields := []interface{}{"event_type", e.Type}
+	var data struct {
+		GroupID      int64  `json:"group_id"`
+		MessageScene string `json:"message_scene"`
+		PeerID       int64  `json:"peer_id"`
+	}
+	if json.Unmarshal(e.RawData, &data) == nil {
+		if data.GroupID == 0 && data.MessageScene == string(SceneGroup) {
+			data.GroupID = data.PeerID
+		}
+		if data.GroupID != 0 {
+			fields = append(fields, "group_id", data.GroupID)
+		}
+	}
+	return fields
+}
+
 func (s *Session) reconnect() {
 
-	s.Logger.Debugf("called")
+	s.log().Debugf("called")
 
 	var err error
 
@@ -289,25 +366,44 @@ func (s *Session) reconnect() {
 
 		wait := time.Duration(1)
 
+		wake := make(chan struct{}, 1)
+		s.reconnectMu.Lock()
+		s.reconnectWake = wake
+		s.reconnectMu.Unlock()
+		defer func() {
+			s.reconnectMu.Lock()
+			if s.reconnectWake == wake {
+				s.reconnectWake = nil
+			}
+			s.reconnectMu.Unlock()
+		}()
+
 		for {
-			s.Logger.Info("trying to reconnect to gateway")
+			s.log().Info("trying to reconnect to gateway")
 
 			err = s.Open()
 			if err == nil {
-				s.Logger.Info("successfully reconnected to gateway")
+				s.metrics().ObserveReconnect(nil)
+				s.log().Info("successfully reconnected to gateway")
 				return
 			}
 
 			// Certain race conditions can call reconnect() twice. If this happens, we
 			// just break out of the reconnect loop
 			if errors.Is(err, ErrWSAlreadyOpen) {
-				s.Logger.Info("Websocket already exists, no need to reconnect")
+				s.log().Info("Websocket already exists, no need to reconnect")
 				return
 			}
 
-			s.Logger.Errorf("error reconnecting to gateway, %s", err)
+			s.metrics().ObserveReconnect(err)
+
+			s.log().Errorf("error reconnecting to gateway, %s", err)
 
-			<-time.After(wait * time.Second)
+			select {
+			case <-time.After(wait * time.Second):
+			case <-wake:
+				s.log().Info("reconnect requested, skipping backoff")
+			}
 			wait *= 2
 			if wait > 600 {
 				wait = 600
@@ -316,6 +412,21 @@ func (s *Session) reconnect() {
 	}
 }
 
+// wakeReconnect cuts the backoff of a running reconnect loop short. It
+// reports whether a reconnect loop was running.
+func (s *Session) wakeReconnect() bool {
+	s.reconnectMu.Lock()
+	defer s.reconnectMu.Unlock()
+	if s.reconnectWake == nil {
+		return false
+	}
+	select {
+	case s.reconnectWake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
 // Close closes a websocket and stops all listening/heartbeat goroutines.
 func (s *Session) Close() error {
 	return s.CloseWithCode(websocket.CloseNormalClosure)
@@ -325,33 +436,34 @@ func (s *Session) Close() error {
 // listening/heartbeat goroutines.
 func (s *Session) CloseWithCode(closeCode int) (err error) {
 
-	s.Logger.Debug("called")
+	s.log().Debug("called")
 	s.Lock()
 
+	connected := s.wsConn != nil
 	if s.listening != nil {
-		s.Logger.Info("closing listening channel")
+		s.log().Info("closing listening channel")
 		close(s.listening)
 		s.listening = nil
 	}
 
 	if s.wsConn != nil {
 
-		s.Logger.Info("sending close frame")
+		s.log().Info("sending close frame")
 		// To cleanly close a connection, a client should send a close
 		// frame and wait for the server to close the connection.
 		s.wsMutex.Lock()
 		err = s.wsConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, ""))
 		s.wsMutex.Unlock()
 		if err != nil {
-			s.Logger.Warnf("error closing websocket, %s", err)
+			s.log().Warnf("error closing websocket, %s", err)
 		}
 
 		time.Sleep(1 * time.Second)
 
-		s.Logger.Infof("closing gateway websocket")
+		s.log().Infof("closing gateway websocket")
 		err = s.wsConn.Close()
 		if err != nil {
-			s.Logger.Warnf("error closing websocket, %s", err)
+			s.log().Warnf("error closing websocket, %s", err)
 		}
 
 		s.wsConn = nil
@@ -359,5 +471,9 @@ func (s *Session) CloseWithCode(closeCode int) (err error) {
 
 	s.Unlock()
 
+	if connected {
+		s.handleEvent(disconnectEventType, &Disconnect{Time: time.Now()})
+	}
+
 	return
 }